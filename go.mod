@@ -9,6 +9,7 @@ require (
 	github.com/jinzhu/now v1.1.1
 	github.com/slack-go/slack v0.6.5
 	github.com/spf13/viper v1.7.0
+	go.etcd.io/bbolt v1.3.5
 	go.uber.org/zap v1.10.0
 	golang.org/x/net v0.0.0-20200602114024-627f9648deb9
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
@@ -0,0 +1,302 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dombo/hiberBot/pkg/bot/rota"
+	"github.com/go-joe/joe"
+	slackAPI "github.com/slack-go/slack"
+)
+
+// handover collects what the next person on the first tier should know about a shift
+type handover struct {
+	Team     string
+	Outgoing string    // rota email of who handed over, set when the shift ends
+	From     time.Time // start of the shift
+	To       time.Time // end of the shift, zero while it is running
+	Notes    []handoverNote
+	Log      []handoverEntry // what happened during the shift, e.g. postmortems created
+}
+
+type handoverNote struct {
+	AuthorID string
+	Text     string
+	At       time.Time
+}
+
+type handoverEntry struct {
+	Kind string
+	Text string
+	At   time.Time
+}
+
+// handoverKey is the store key of the handover being collected for a team
+func handoverKey(team string) string {
+	return "handover." + team
+}
+
+// loadHandover returns the team's handover, or a new one for the current shift
+func (b *Bot) loadHandover(team string) (handover, error) {
+	var h handover
+	ok, err := b.Store.Get(handoverKey(team), &h)
+	if err != nil {
+		return h, fmt.Errorf("failed to load the %s handover %v", team, err)
+	}
+	if !ok {
+		from, _ := b.Tiers[0].Window.Around(b.teamNow(team))
+		h = handover{Team: team, From: from}
+	}
+	return h, nil
+}
+
+func (b *Bot) saveHandover(h handover) error {
+	if err := b.Store.Set(handoverKey(h.Team), h); err != nil {
+		return fmt.Errorf("failed to save the %s handover %v", h.Team, err)
+	}
+	return nil
+}
+
+// recordForHandover adds something that happened to the team's next handover digest
+func (b *Bot) recordForHandover(team, kind, text string) {
+	h, err := b.loadHandover(team)
+	if err == nil {
+		h.Log = append(h.Log, handoverEntry{Kind: kind, Text: text, At: time.Now()})
+		err = b.saveHandover(h)
+	}
+	if err != nil {
+		b.Logger.Error(err.Error())
+	}
+}
+
+// DailySendLevel1TheCongratulationsMessage thanks the first tier at the end of
+// their day and asks them for handover notes
+func (b *Bot) DailySendLevel1TheCongratulationsMessage(team string) {
+	shift, err := rota.OnCall(b.Rota, team, b.Tiers[0], b.teamNow(team))
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("level 1 rota user retrieval error %v", err))
+		return
+	}
+
+	h, err := b.loadHandover(team)
+	if err != nil {
+		b.Logger.Error(err.Error())
+		return
+	}
+	h.Outgoing = shift.Email
+	h.To = time.Now()
+	if err := b.saveHandover(h); err != nil {
+		b.Logger.Error(err.Error())
+		return
+	}
+
+	level1, err := b.Identities.User(shift.Email)
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("level 1 rota user retrieval error %v", err))
+		return
+	}
+
+	b.sendMessage(MessageCongratulations, team, level1.ID)
+}
+
+// Handoff answers "handoff note [team] <notes>" and "handoff show [team]"
+func (b *Bot) Handoff(message joe.Message) error {
+	args := strings.TrimSpace(message.Text[len("handoff"):])
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		message.Respond("Try @%s handoff note <notes> or @%s handoff show", b.Bot.Name, b.Bot.Name)
+		return nil
+	}
+
+	// An explicit team comes right after the sub command
+	var team string
+	rest := strings.TrimSpace(args[len(fields[0]):])
+	if len(fields) > 1 {
+		if t, ok := b.conf.Team(fields[1]); ok {
+			team = t.Name
+			rest = strings.TrimSpace(rest[len(fields[1]):])
+		}
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "note":
+		if rest == "" {
+			message.Respond("Try @%s handoff note <notes>", b.Bot.Name)
+			return nil
+		}
+		return b.AddHandoffNote(message, team, rest)
+	case "show":
+		return b.ShowHandoff(message, team)
+	}
+
+	message.Respond("Try @%s handoff note <notes> or @%s handoff show", b.Bot.Name, b.Bot.Name)
+	return nil
+}
+
+// AddHandoffNote adds a note for the next person on the first tier. Without
+// a team, notes of someone who just finished a shift go to that team and
+// anyone else's to the team of the channel.
+func (b *Bot) AddHandoffNote(message joe.Message, team, text string) error {
+	if team == "" {
+		team = b.handoffTeam(message)
+	}
+
+	h, err := b.loadHandover(team)
+	if err != nil {
+		return err
+	}
+	h.Notes = append(h.Notes, handoverNote{AuthorID: message.AuthorID, Text: text, At: time.Now()})
+	if err := b.saveHandover(h); err != nil {
+		return err
+	}
+
+	message.Respond("Thanks, I'll pass that on to whoever is on %s for %s next", b.Tiers[0].Name, team)
+	return nil
+}
+
+// ShowHandoff shows what the next handover digest of the team will contain so far
+func (b *Bot) ShowHandoff(message joe.Message, team string) error {
+	if team == "" {
+		team = b.handoffTeam(message)
+	}
+
+	h, err := b.loadHandover(team)
+	if err != nil {
+		return err
+	}
+	if h.To.IsZero() {
+		h.To = time.Now()
+	}
+
+	message.Respond("%s", b.formatHandover(h, time.Now()))
+	return nil
+}
+
+// handoffTeam picks the team a handoff command without a team is about: the
+// team of a shift the author just finished, else the team of the channel
+func (b *Bot) handoffTeam(message joe.Message) string {
+	team := b.conf.TeamForChannel(message.Channel).Name
+	if author, err := b.emailForUser(message.AuthorID); err == nil {
+		for _, t := range b.conf.RotaTeams() {
+			h, err := b.loadHandover(t.Name)
+			if err != nil {
+				b.Logger.Error(err.Error())
+				continue
+			}
+			if !h.To.IsZero() && b.Identities.Same(h.Outgoing, author) {
+				return t.Name
+			}
+		}
+	}
+	return team
+}
+
+// DailySendHandoverDigest posts what happened during the last shift to the
+// team's questions channel, the incoming first tier gets it with the runbook
+func (b *Bot) DailySendHandoverDigest(team string) {
+	h, err := b.loadHandover(team)
+	if err != nil {
+		b.Logger.Error(err.Error())
+		return
+	}
+	if h.To.IsZero() {
+		b.Logger.Info(fmt.Sprintf("no finished %s shift to hand over", team))
+		return
+	}
+
+	if teamConf, ok := b.conf.Team(team); ok && teamConf.QuestionsChannel != "" {
+		b.notify(teamConf.QuestionsChannel, b.formatHandover(h, time.Now()))
+	}
+
+	if _, err := b.Store.Delete(handoverKey(team)); err != nil {
+		b.Logger.Error(fmt.Sprintf("failed to delete the %s handover %v", team, err))
+	}
+}
+
+// formatHandover renders the digest of a handover, including the questions
+// asked since the shift started that nobody replied to
+func (b *Bot) formatHandover(h handover, until time.Time) string {
+	outgoing := "the last shift"
+	if h.Outgoing != "" {
+		outgoing = b.slackNameForEmail(h.Outgoing)
+	}
+	lines := []string{fmt.Sprintf(":baton: Handover for %s from %s (%s)", h.Team, outgoing, formatShiftSpan(rota.Shift{Start: h.From, End: h.To}))}
+
+	if len(h.Notes) > 0 {
+		lines = append(lines, "*Notes*")
+		for _, n := range h.Notes {
+			lines = append(lines, fmt.Sprintf("• <@%s>: %s", n.AuthorID, n.Text))
+		}
+	}
+
+	if len(h.Log) > 0 {
+		lines = append(lines, "*During the shift*")
+		for _, e := range h.Log {
+			lines = append(lines, fmt.Sprintf("• %s %s: %s", e.At.Format("Mon 15:04"), e.Kind, e.Text))
+		}
+	}
+
+	if teamConf, ok := b.conf.Team(h.Team); ok && teamConf.QuestionsChannel != "" {
+		questions, err := b.unansweredQuestions(teamConf.QuestionsChannel, h.From, until)
+		if err != nil {
+			b.Logger.Error(err.Error())
+		} else if len(questions) > 0 {
+			lines = append(lines, "*Unanswered questions*")
+			lines = append(lines, questions...)
+		}
+	}
+
+	if len(lines) == 1 {
+		lines = append(lines, "A quiet shift, nothing to hand over.")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unansweredQuestions lists the messages people posted to the channel between from and to that have no replies
+func (b *Bot) unansweredQuestions(channelID string, from, to time.Time) ([]string, error) {
+	params := &slackAPI.GetConversationHistoryParameters{
+		ChannelID: channelID,
+		Oldest:    strconv.FormatInt(from.Unix(), 10),
+		Latest:    strconv.FormatInt(to.Unix(), 10),
+		Limit:     200,
+	}
+
+	var lines []string
+	for {
+		history, err := b.Slack.GetConversationHistory(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the history of %s %v", channelID, err)
+		}
+
+		for _, m := range history.Messages {
+			if m.SubType != "" || m.BotID != "" || m.ReplyCount > 0 {
+				continue
+			}
+
+			text := []rune(strings.ReplaceAll(m.Text, "\n", " "))
+			if len(text) > 80 {
+				text = append(text[:80], '…')
+			}
+
+			link, err := b.Slack.GetPermalink(&slackAPI.PermalinkParameters{Channel: channelID, Ts: m.Timestamp})
+			if err != nil {
+				lines = append(lines, fmt.Sprintf("• <@%s>: %s", m.User, string(text)))
+			} else {
+				lines = append(lines, fmt.Sprintf("• <@%s>: <%s|%s>", m.User, link, string(text)))
+			}
+		}
+
+		if !history.HasMore || history.ResponseMetaData.NextCursor == "" {
+			break
+		}
+		params.Cursor = history.ResponseMetaData.NextCursor
+	}
+
+	// The history is newest first
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines, nil
+}
@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dombo/hiberBot/pkg/bot/rota"
+)
+
+// holidaySource returns the holidays of the team's region, or nil when the team has no region
+func (b *Bot) holidaySource(team string) rota.HolidaySource {
+	teamConf, ok := b.conf.Team(team)
+	if !ok || teamConf.Region == "" {
+		return nil
+	}
+	return b.Holidays[strings.ToLower(teamConf.Region)]
+}
+
+// publicHoliday returns the holiday of the team on the day of at, or nil
+func (b *Bot) publicHoliday(team string, at time.Time) *rota.Holiday {
+	src := b.holidaySource(team)
+	if src == nil {
+		return nil
+	}
+
+	holiday, err := rota.HolidayOn(src, at)
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("failed to look up the holidays of %s %v", team, err))
+		return nil
+	}
+	return holiday
+}
+
+// skipOnHoliday reports whether the team's daily reminders are off today
+func (b *Bot) skipOnHoliday(team string) bool {
+	teamConf, ok := b.conf.Team(team)
+	if !ok || teamConf.OnHoliday != OnHolidaySkip {
+		return false
+	}
+
+	holiday := b.publicHoliday(team, time.Now().In(teamConf.Location()))
+	if holiday == nil {
+		return false
+	}
+	b.Logger.Info(fmt.Sprintf("skipping the %s reminders for %s", team, holiday.Name))
+	return true
+}
+
+// holidays returns the public holidays of the team between from and to
+func (b *Bot) holidays(team string, from, to time.Time) ([]rota.Holiday, error) {
+	src := b.holidaySource(team)
+	if src == nil {
+		return nil, nil
+	}
+	return src.Holidays(from, to)
+}
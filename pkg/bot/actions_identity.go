@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dombo/hiberBot/pkg/bot/identity"
+	"github.com/go-joe/joe"
+)
+
+// mailtoPattern matches an email as Slack formats it: <mailto:alice@example.com|alice@example.com>
+var mailtoPattern = regexp.MustCompile(`^<mailto:([^|>]+)(?:\|[^>]*)?>$`)
+
+// Whois answers "whois <email|@user>" with how the bot maps the person between the rota and Slack
+func (b *Bot) Whois(message joe.Message) error {
+	arg := strings.TrimSpace(message.Text[len("whois"):])
+
+	var id identity.Identity
+	if mention := mentionPattern.FindStringSubmatch(arg); mention != nil {
+		var err error
+		id, err = b.Identities.ForUser(mention[1])
+		if err != nil {
+			message.Respond("I couldn't look up <@%s>: %v", mention[1], err)
+			return nil
+		}
+	} else {
+		email := arg
+		if mailto := mailtoPattern.FindStringSubmatch(arg); mailto != nil {
+			email = mailto[1]
+		}
+		if !strings.Contains(email, "@") {
+			message.Respond("Try @%s whois <email|@user>", b.Bot.Name)
+			return nil
+		}
+		id = b.Identities.Lookup(email)
+	}
+
+	lines := []string{fmt.Sprintf("Rota email: %s", id.Email)}
+	if len(id.Aliases) > 0 {
+		lines = append(lines, fmt.Sprintf("Aliases: %s", strings.Join(id.Aliases, ", ")))
+	}
+	if id.User != nil {
+		lines = append(lines, fmt.Sprintf("Slack user: <@%s> (%s), found through %s", id.User.ID, id.User.Name, id.Via))
+	} else {
+		lines = append(lines, fmt.Sprintf("Slack user: none, %v", id.Err))
+	}
+	lines = append(lines, fmt.Sprintf("Looked up again after %s", id.Expires.Format("Mon 2 Jan 15:04")))
+
+	message.Respond("%s", strings.Join(lines, "\n"))
+	return nil
+}
@@ -0,0 +1,699 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dombo/hiberBot/pkg/bot/postmortem"
+	"github.com/dombo/hiberBot/pkg/bot/rota"
+	"github.com/go-joe/joe"
+	slackAPI "github.com/slack-go/slack"
+)
+
+// Block Kit action ID of the button creating the postmortem of an incident
+const actionIncidentPostmortem = "incident_postmortem"
+
+// Roles every incident has, teams can add their own with incident.roles
+const (
+	incidentCommander = "commander"
+	incidentComms     = "comms"
+	incidentScribe    = "scribe"
+)
+
+// incidentRoleNames are how the built-in roles are shown
+var incidentRoleNames = map[string]string{
+	incidentCommander: "Commander",
+	incidentComms:     "Comms lead",
+	incidentScribe:    "Scribe",
+}
+
+// incidentNextKey holds the number of the next incident
+const incidentNextKey = "incidents.next"
+
+// incident is a declared incident and its dedicated channel
+type incident struct {
+	ID         string // e.g. INC-12
+	Severity   string // name of the severity, see Config.IncidentSeverities
+	Title      string
+	Team       string
+	Channel    string // Slack channel ID of the incident channel
+	Summary    string // timestamp of the pinned summary in the incident channel
+	Declarer   string // Slack user ID
+	Declared   time.Time
+	Resolver   string
+	Resolved   time.Time // zero while the incident is open
+	LastUpdate time.Time // when the last status update was posted
+	Archived   bool
+	Postmortem string            // ID of the postmortem once it was created
+	DueBy      time.Time         // when a mandatory postmortem has to be published by
+	Roles      map[string]string // Slack user ID of who holds each role, see Config.IncidentRoles
+	Timeline   []incidentEntry
+}
+
+// incidentEntry is something that happened during an incident
+type incidentEntry struct {
+	At    time.Time
+	Actor string // Slack user ID
+	Text  string
+}
+
+func incidentKey(id string) string {
+	return "incident." + id
+}
+
+func (inc incident) name() string {
+	return fmt.Sprintf("%s %s: %s", inc.ID, inc.Severity, inc.Title)
+}
+
+// lastUpdate returns when the last status update was posted, incidents
+// declared before updates were tracked count from when they were declared
+func (inc incident) lastUpdate() time.Time {
+	if inc.LastUpdate.IsZero() {
+		return inc.Declared
+	}
+	return inc.LastUpdate
+}
+
+func (inc *incident) log(actor, text string) {
+	inc.Timeline = append(inc.Timeline, incidentEntry{At: time.Now(), Actor: actor, Text: text})
+}
+
+func (b *Bot) loadIncident(id string) (incident, bool, error) {
+	var inc incident
+	ok, err := b.Store.Get(incidentKey(strings.ToUpper(id)), &inc)
+	if err != nil {
+		return inc, false, fmt.Errorf("failed to load incident %s %v", id, err)
+	}
+	return inc, ok, nil
+}
+
+func (b *Bot) saveIncident(inc incident) error {
+	if err := b.Store.Set(incidentKey(inc.ID), inc); err != nil {
+		return fmt.Errorf("failed to save incident %s %v", inc.ID, err)
+	}
+	return nil
+}
+
+// incidents returns every incident the bot knows, newest first
+func (b *Bot) incidents() ([]incident, error) {
+	keys, err := b.Store.Keys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incidents %v", err)
+	}
+
+	var incidents []incident
+	for _, key := range keys {
+		if !strings.HasPrefix(key, incidentKey("")) {
+			continue
+		}
+		var inc incident
+		if ok, err := b.Store.Get(key, &inc); err != nil {
+			return nil, fmt.Errorf("failed to load incident %s %v", key, err)
+		} else if ok {
+			incidents = append(incidents, inc)
+		}
+	}
+
+	sort.Slice(incidents, func(i, j int) bool { return incidents[i].Declared.After(incidents[j].Declared) })
+	return incidents, nil
+}
+
+// openIncidents returns the incidents of the team that aren't resolved yet
+func (b *Bot) openIncidents(team string) []incident {
+	all, err := b.incidents()
+	if err != nil {
+		b.Logger.Error(err.Error())
+		return nil
+	}
+
+	var open []incident
+	for _, inc := range all {
+		if inc.Team == team && inc.Resolved.IsZero() {
+			open = append(open, inc)
+		}
+	}
+	return open
+}
+
+// incidentForChannel returns the incident whose channel it is
+func (b *Bot) incidentForChannel(channelID string) (incident, bool, error) {
+	all, err := b.incidents()
+	if err != nil {
+		return incident{}, false, err
+	}
+	for _, inc := range all {
+		if inc.Channel == channelID {
+			return inc, true, nil
+		}
+	}
+	return incident{}, false, nil
+}
+
+// findIncident returns the incident of the ID, or of the channel without an ID
+func (b *Bot) findIncident(channelID, id string) (incident, bool, error) {
+	if id != "" {
+		return b.loadIncident(id)
+	}
+	return b.incidentForChannel(channelID)
+}
+
+// Incident answers "incident declare <sev> <title>", "incident resolve [id]",
+// "incident role [<role> @user] [id]", "incident timeline [id]" and "incident list"
+func (b *Bot) Incident(message joe.Message) error {
+	args := strings.TrimSpace(strings.TrimPrefix(message.Text, "incident"))
+	fields := strings.Fields(args)
+	usage := fmt.Sprintf("Try @%s incident declare <%s> <title>, @%s incident update <text>, @%s incident resolve [id], "+
+		"@%s incident role <role> @someone, @%s incident timeline [id] or @%s incident list",
+		b.Bot.Name, strings.Join(b.severityNames(), "|"), b.Bot.Name, b.Bot.Name, b.Bot.Name, b.Bot.Name, b.Bot.Name)
+	if len(fields) == 0 {
+		message.Respond(usage)
+		return nil
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "declare":
+		if len(fields) < 3 {
+			message.Respond("Try @%s incident declare <%s> <title>", b.Bot.Name, strings.Join(b.severityNames(), "|"))
+			return nil
+		}
+		rest := strings.TrimSpace(args[len(fields[0]):])
+		title := strings.TrimSpace(rest[len(fields[1]):])
+		return b.DeclareIncident(message, fields[1], title)
+	case "resolve":
+		id := ""
+		if len(fields) > 1 {
+			id = fields[1]
+		}
+		return b.ResolveIncident(message, id)
+	case "update":
+		text := strings.TrimSpace(args[len(fields[0]):])
+		if text == "" {
+			message.Respond("Try @%s incident update <what's happening> in the incident's channel", b.Bot.Name)
+			return nil
+		}
+		return b.IncidentUpdate(message, text)
+	case "role", "roles":
+		return b.IncidentRole(message, fields[1:])
+	case "timeline":
+		id := ""
+		if len(fields) > 1 {
+			id = fields[1]
+		}
+		return b.IncidentTimeline(message, id)
+	case "list":
+		return b.ListIncidents(message)
+	}
+
+	message.Respond(usage)
+	return nil
+}
+
+// DeclareIncident allocates an incident ID, creates the incident's channel,
+// pages whoever the severity's policy names and pins a summary with a button
+// to create the postmortem
+func (b *Bot) DeclareIncident(message joe.Message, severity, title string) error {
+	policy, ok := b.conf.IncidentSeverity(severity)
+	if !ok {
+		message.Respond("%s isn't a severity, try one of %s", severity, strings.Join(b.severityNames(), ", "))
+		return nil
+	}
+
+	var next int
+	if _, err := b.Store.Get(incidentNextKey, &next); err != nil {
+		return fmt.Errorf("failed to allocate an incident ID %v", err)
+	}
+	if next == 0 {
+		next = 1
+	}
+	if err := b.Store.Set(incidentNextKey, next+1); err != nil {
+		return fmt.Errorf("failed to allocate an incident ID %v", err)
+	}
+
+	team := b.conf.TeamForChannel(message.Channel)
+	inc := incident{
+		ID:         fmt.Sprintf("INC-%d", next),
+		Severity:   policy.Name,
+		Title:      title,
+		Team:       team.Name,
+		Declarer:   message.AuthorID,
+		Declared:   time.Now(),
+		LastUpdate: time.Now(),
+		Roles:      map[string]string{},
+	}
+	inc.log(message.AuthorID, "Declared")
+
+	// The first tier leads the incident until someone takes over
+	commander := message.AuthorID
+	if level1, err := b.getRotaLevel1(team.Name); err == nil && level1 != nil {
+		commander = level1.ID
+	} else if err != nil && err != rota.ErrNoShift {
+		b.Logger.Error(fmt.Sprintf("level 1 rota user retrieval error %v", err))
+	}
+	inc.Roles[incidentCommander] = commander
+	inc.log(message.AuthorID, fmt.Sprintf("<@%s> is %s", commander, incidentRoleName(incidentCommander)))
+
+	channel, err := b.createIncidentChannel(inc)
+	if err != nil {
+		message.Respond("Sorry, I failed to create a channel for %s: %v", inc.ID, err)
+		return nil
+	}
+	inc.Channel = channel.ID
+
+	invite := []string{message.AuthorID}
+	if !contains(invite, commander) {
+		invite = append(invite, commander)
+	}
+	for _, user := range b.pageIncident(&inc, policy) {
+		if !contains(invite, user) {
+			invite = append(invite, user)
+		}
+	}
+	if _, err := b.Slack.InviteUsersToConversation(inc.Channel, invite...); err != nil {
+		b.Logger.Error(fmt.Sprintf("failed to invite people to %s %v", inc.ID, err))
+	}
+
+	_, timestamp, err := b.Slack.PostMessage(inc.Channel,
+		slackAPI.MsgOptionText(inc.name(), false),
+		slackAPI.MsgOptionBlocks(b.incidentBlocks(inc)...),
+	)
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("failed to post the summary of %s %v", inc.ID, err))
+	} else {
+		inc.Summary = timestamp
+		if err := b.Slack.AddPin(inc.Channel, slackAPI.NewRefToMessage(inc.Channel, timestamp)); err != nil {
+			b.Logger.Error(fmt.Sprintf("failed to pin the summary of %s %v", inc.ID, err))
+		}
+	}
+
+	b.setIncidentTopic(inc)
+
+	if err := b.saveIncident(inc); err != nil {
+		return err
+	}
+
+	for _, channelID := range policy.Notify {
+		b.notify(channelID, fmt.Sprintf(":rotating_light: <@%s> declared %s, follow along in <#%s>", inc.Declarer, inc.name(), inc.Channel))
+	}
+	if interval := policy.UpdateInterval(); interval > 0 {
+		b.scheduleIncidentUpdateReminder(inc.ID, interval)
+	}
+
+	b.recordForHandover(team.Name, "Incident", fmt.Sprintf("%s declared in <#%s>", inc.name(), inc.Channel))
+	message.Respond("Declared %s, let's continue in <#%s>", inc.name(), inc.Channel)
+	return nil
+}
+
+// createIncidentChannel creates the channel #inc-<date>-<slug>, numbered when the name is taken.
+// The date is the team's, titles without any letters or digits use the incident ID instead.
+func (b *Bot) createIncidentChannel(inc incident) (*slackAPI.Channel, error) {
+	teamConf, _ := b.conf.Team(inc.Team)
+	slug := channelSlug(inc.Title)
+	if slug == "" {
+		slug = channelSlug(inc.ID)
+	}
+	name := fmt.Sprintf("inc-%s-%s", inc.Declared.In(teamConf.Location()).Format("2006-01-02"), slug)
+	if len(name) > 75 {
+		name = strings.TrimRight(name[:75], "-")
+	}
+
+	candidate := name
+	for i := 2; ; i++ {
+		channel, err := b.Slack.CreateConversation(candidate, false)
+		if err == nil {
+			return channel, nil
+		}
+		if err.Error() != "name_taken" || i > 9 {
+			return nil, err
+		}
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+}
+
+// ResolveIncident marks the incident of the ID, or of the channel, as
+// resolved and archives its channel after the configured delay
+func (b *Bot) ResolveIncident(message joe.Message, id string) error {
+	inc, ok, err := b.findIncident(message.Channel, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		message.Respond("I don't know that incident, try @%s incident resolve <id> or run it in the incident's channel", b.Bot.Name)
+		return nil
+	}
+	if !inc.Resolved.IsZero() {
+		message.Respond("%s was resolved by <@%s> at %s", inc.ID, inc.Resolver, inc.Resolved.Format("Mon 2 Jan 15:04"))
+		return nil
+	}
+
+	inc.Resolved = time.Now()
+	inc.Resolver = message.AuthorID
+	inc.log(message.AuthorID, "Resolved")
+
+	policy, _ := b.conf.IncidentSeverity(inc.Severity)
+	if policy.Postmortem {
+		inc.DueBy = b.businessDaysAfter(inc.Team, inc.Resolved, policy.PostmortemDueDays())
+	}
+	if err := b.saveIncident(inc); err != nil {
+		return err
+	}
+	b.updateIncidentSummary(inc)
+	if inc.Postmortem != "" && !inc.DueBy.IsZero() && b.Postmortems != nil {
+		if err := b.Postmortems.Update(inc.Postmortem, map[string]string{postmortem.FieldDue: inc.DueBy.Format("Mon 2 Jan 2006")}); err != nil {
+			b.Logger.Error(fmt.Sprintf("failed to update postmortem %s %v", inc.Postmortem, err))
+		}
+	}
+
+	delay := b.conf.IncidentArchiveAfter()
+	text := fmt.Sprintf(":white_check_mark: <@%s> resolved %s after %s. I'll archive this channel in %s.",
+		inc.Resolver, inc.name(), formatDuration(inc.Resolved.Sub(inc.Declared)), formatDuration(delay))
+	switch {
+	case inc.DueBy.IsZero():
+	case inc.Postmortem == "":
+		text += fmt.Sprintf(" A %s incident needs a postmortem, published by %s.", inc.Severity, inc.DueBy.Format("Mon 2 Jan"))
+	default:
+		text += fmt.Sprintf(" The postmortem is due to be published by %s.", inc.DueBy.Format("Mon 2 Jan"))
+	}
+	b.notify(inc.Channel, text)
+	for _, channelID := range policy.Notify {
+		b.notify(channelID, fmt.Sprintf(":white_check_mark: %s is resolved", inc.name()))
+	}
+	if message.Channel != inc.Channel {
+		message.Respond("Resolved %s", inc.name())
+	}
+
+	b.recordForHandover(inc.Team, "Incident", fmt.Sprintf("%s resolved after %s", inc.name(), formatDuration(inc.Resolved.Sub(inc.Declared))))
+	b.scheduleIncidentArchive(inc.ID, delay)
+	return nil
+}
+
+// IncidentRole answers "incident role <role> @user [id]" by handing the role
+// to the person and "incident role [id]" with who holds the roles
+func (b *Bot) IncidentRole(message joe.Message, args []string) error {
+	usage := fmt.Sprintf("Try @%s incident role <%s> @someone [id]", b.Bot.Name, strings.Join(b.conf.IncidentRoles(), "|"))
+
+	var role, user, id string
+	switch {
+	case len(args) <= 1:
+		if len(args) == 1 {
+			id = args[0]
+		}
+	case len(args) <= 3:
+		role = strings.ToLower(args[0])
+		mention := mentionPattern.FindStringSubmatch(args[1])
+		if mention == nil {
+			message.Respond(usage)
+			return nil
+		}
+		user = mention[1]
+		if len(args) == 3 {
+			id = args[2]
+		}
+	default:
+		message.Respond(usage)
+		return nil
+	}
+
+	inc, ok, err := b.findIncident(message.Channel, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		message.Respond("I don't know that incident, add its ID or run this in the incident's channel")
+		return nil
+	}
+
+	if role == "" {
+		lines := []string{fmt.Sprintf("Roles of %s:", inc.name())}
+		for _, r := range b.conf.IncidentRoles() {
+			lines = append(lines, fmt.Sprintf("• %s: %s", incidentRoleName(r), mentionOrNobody(inc.Roles[r])))
+		}
+		message.Respond("%s", strings.Join(lines, "\n"))
+		return nil
+	}
+	if !contains(b.conf.IncidentRoles(), role) {
+		message.Respond(usage)
+		return nil
+	}
+
+	previous := inc.Roles[role]
+	if previous == user {
+		message.Respond("<@%s> is %s already", user, incidentRoleName(role))
+		return nil
+	}
+	if inc.Roles == nil {
+		inc.Roles = map[string]string{}
+	}
+	inc.Roles[role] = user
+
+	text := fmt.Sprintf("<@%s> is %s", user, incidentRoleName(role))
+	if previous != "" {
+		text = fmt.Sprintf("<@%s> handed %s over to <@%s>", previous, incidentRoleName(role), user)
+	}
+	inc.log(message.AuthorID, text)
+	if err := b.saveIncident(inc); err != nil {
+		return err
+	}
+
+	if _, err := b.Slack.InviteUsersToConversation(inc.Channel, user); err != nil && err.Error() != "already_in_channel" {
+		b.Logger.Error(fmt.Sprintf("failed to invite <@%s> to %s %v", user, inc.ID, err))
+	}
+	b.setIncidentTopic(inc)
+	b.updateIncidentSummary(inc)
+
+	b.notify(inc.Channel, text)
+	if message.Channel != inc.Channel {
+		message.Respond("%s", text)
+	}
+	return nil
+}
+
+// setIncidentTopic shows the incident and who holds its roles in the channel topic
+func (b *Bot) setIncidentTopic(inc incident) {
+	parts := []string{inc.name()}
+	for _, role := range b.conf.IncidentRoles() {
+		if user := inc.Roles[role]; user != "" {
+			parts = append(parts, fmt.Sprintf("%s: <@%s>", incidentRoleName(role), user))
+		}
+	}
+
+	topic := []rune(strings.Join(parts, " | "))
+	if len(topic) > 250 {
+		topic = append(topic[:249], '…')
+	}
+	if _, err := b.Slack.SetTopicOfConversation(inc.Channel, string(topic)); err != nil {
+		b.Logger.Error(fmt.Sprintf("failed to set the topic of %s %v", inc.ID, err))
+	}
+}
+
+// ListIncidents responds with the open incidents and the ones resolved in the last week
+func (b *Bot) ListIncidents(message joe.Message) error {
+	all, err := b.incidents()
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, inc := range all {
+		switch {
+		case inc.Resolved.IsZero():
+			lines = append(lines, fmt.Sprintf("• :rotating_light: %s in <#%s> since %s", inc.name(), inc.Channel, inc.Declared.Format("Mon 2 Jan 15:04")))
+		case time.Since(inc.Resolved) < 7*24*time.Hour:
+			lines = append(lines, fmt.Sprintf("• %s resolved %s", inc.name(), inc.Resolved.Format("Mon 2 Jan 15:04")))
+		}
+	}
+	if len(lines) == 0 {
+		message.Respond("There are no open incidents and none were resolved in the last week")
+		return nil
+	}
+	message.Respond("%s", strings.Join(lines, "\n"))
+	return nil
+}
+
+// IncidentTimeline responds with what happened during the incident of the ID,
+// or of the channel, in the team's time zone
+func (b *Bot) IncidentTimeline(message joe.Message, id string) error {
+	inc, ok, err := b.findIncident(message.Channel, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		message.Respond("I don't know that incident, try @%s incident timeline <id> or ask in the incident's channel", b.Bot.Name)
+		return nil
+	}
+	if len(inc.Timeline) == 0 {
+		message.Respond("Nothing was recorded for %s", inc.ID)
+		return nil
+	}
+
+	teamConf, _ := b.conf.Team(inc.Team)
+	lines := []string{fmt.Sprintf("Timeline of %s:", inc.name())}
+	for _, e := range inc.Timeline {
+		line := fmt.Sprintf("• %s %s", e.At.In(teamConf.Location()).Format("Mon 2 Jan 15:04"), e.Text)
+		if e.Actor != "" {
+			line += fmt.Sprintf(" (<@%s>)", e.Actor)
+		}
+		lines = append(lines, line)
+	}
+	message.Respond("%s", strings.Join(lines, "\n"))
+	return nil
+}
+
+// IncidentPostmortemResponse creates the postmortem of an incident once someone clicked the button in its summary
+func (b *Bot) IncidentPostmortemResponse(callback slackAPI.InteractionCallback, action *slackAPI.BlockAction) error {
+	inc, ok, err := b.loadIncident(action.Value)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("unknown incident %s", action.Value)
+	}
+	if inc.Postmortem != "" {
+		b.updateIncidentSummary(inc)
+		return nil
+	}
+	if b.Postmortems == nil {
+		b.notify(callback.Channel.ID, "Postmortems need Google Docs and Drive credentials, which aren't configured")
+		return nil
+	}
+
+	if _, err := b.createIncidentPostmortem(inc, inc.name(), callback.User.ID); err != nil {
+		b.notify(callback.Channel.ID, "Sorry, I failed to create the postmortem")
+		return err
+	}
+	return nil
+}
+
+// ArchiveIncidentChannel archives the channel of a resolved incident
+func (b *Bot) ArchiveIncidentChannel(id string) {
+	inc, ok, err := b.loadIncident(id)
+	if err != nil {
+		b.Logger.Error(err.Error())
+		return
+	}
+	if !ok || inc.Resolved.IsZero() || inc.Archived {
+		return
+	}
+
+	if err := b.Slack.ArchiveConversation(inc.Channel); err != nil {
+		b.Logger.Error(fmt.Sprintf("failed to archive the channel of %s %v", inc.ID, err))
+		return
+	}
+	inc.Archived = true
+	inc.log("", "Archived the channel")
+	if err := b.saveIncident(inc); err != nil {
+		b.Logger.Error(err.Error())
+	}
+}
+
+// scheduleIncidentArchive emits the archive event of the incident after the delay
+func (b *Bot) scheduleIncidentArchive(id string, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		b.Brain.Emit(IncidentArchiveEvent{ID: id})
+	})
+}
+
+// scheduleIncidentTimers picks up the status update reminders and the
+// channel archiving of incidents from before a restart
+func (b *Bot) scheduleIncidentTimers() {
+	all, err := b.incidents()
+	if err != nil {
+		b.Logger.Error(err.Error())
+		return
+	}
+	for _, inc := range all {
+		policy, _ := b.conf.IncidentSeverity(inc.Severity)
+		switch {
+		case inc.Resolved.IsZero() && policy.UpdateInterval() > 0:
+			b.scheduleIncidentUpdateReminder(inc.ID, time.Until(inc.lastUpdate().Add(policy.UpdateInterval())))
+		case inc.Resolved.IsZero():
+		case !inc.Archived:
+			b.scheduleIncidentArchive(inc.ID, time.Until(inc.Resolved.Add(b.conf.IncidentArchiveAfter())))
+		}
+	}
+}
+
+// updateIncidentSummary refreshes the pinned summary of the incident
+func (b *Bot) updateIncidentSummary(inc incident) {
+	if inc.Summary == "" {
+		return
+	}
+	_, _, _, err := b.Slack.UpdateMessage(inc.Channel, inc.Summary,
+		slackAPI.MsgOptionText(inc.name(), false),
+		slackAPI.MsgOptionBlocks(b.incidentBlocks(inc)...),
+	)
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("failed to update the summary of %s %v", inc.ID, err))
+	}
+}
+
+// incidentBlocks renders the pinned summary of an incident
+func (b *Bot) incidentBlocks(inc incident) []slackAPI.Block {
+	status := fmt.Sprintf("Open since %s", inc.Declared.Format("Mon 2 Jan 15:04"))
+	if !inc.Resolved.IsZero() {
+		status = fmt.Sprintf("Resolved by <@%s> at %s", inc.Resolver, inc.Resolved.Format("Mon 2 Jan 15:04"))
+	}
+
+	blocks := []slackAPI.Block{
+		markdownSection(fmt.Sprintf(":rotating_light: *%s*", inc.name())),
+		markdownSection(fmt.Sprintf("*Team* %s\n*Declared by* <@%s>\n*Status* %s", inc.Team, inc.Declarer, status)),
+	}
+	if policy, ok := b.conf.IncidentSeverity(inc.Severity); ok {
+		blocks = append(blocks, markdownSection(formatSeverityPolicy(policy)))
+	}
+
+	var roles []string
+	for _, role := range b.conf.IncidentRoles() {
+		roles = append(roles, fmt.Sprintf("*%s* %s", incidentRoleName(role), mentionOrNobody(inc.Roles[role])))
+	}
+	blocks = append(blocks, markdownSection(strings.Join(roles, "\n")))
+
+	if inc.Postmortem != "" {
+		if record, ok, err := b.loadPostmortem(inc.Postmortem); err == nil && ok {
+			text := fmt.Sprintf("*Postmortem* <%s|%s>", record.URL, postmortemStatuses[record.Status])
+			if !inc.DueBy.IsZero() {
+				text += fmt.Sprintf(", due by %s", inc.DueBy.Format("Mon 2 Jan"))
+			}
+			blocks = append(blocks, markdownSection(text))
+		}
+	} else {
+		button := slackAPI.NewButtonBlockElement(actionIncidentPostmortem, inc.ID,
+			slackAPI.NewTextBlockObject(slackAPI.PlainTextType, "Create postmortem", false, false))
+		blocks = append(blocks, slackAPI.NewActionBlock("incident_postmortem", button))
+	}
+	return blocks
+}
+
+// incidentRoleName returns how a role is shown, custom roles are shown as configured
+func incidentRoleName(role string) string {
+	if name, ok := incidentRoleNames[role]; ok {
+		return name
+	}
+	return strings.Title(role)
+}
+
+func mentionOrNobody(user string) string {
+	if user == "" {
+		return "nobody"
+	}
+	return fmt.Sprintf("<@%s>", user)
+}
+
+// nonSlug matches what can't be part of a channel name
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// channelSlug turns a title into the lower case dashed form channel names use
+func channelSlug(title string) string {
+	return strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(title), "-"), "-")
+}
+
+// formatDuration renders a duration in hours and minutes, e.g. 2h5m
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Minute {
+		return "less than a minute"
+	}
+	return strings.TrimSuffix(d.String(), "0s")
+}
@@ -0,0 +1,228 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dombo/hiberBot/pkg/bot/postmortem"
+	"github.com/dombo/hiberBot/pkg/bot/rota"
+	"github.com/go-joe/joe"
+)
+
+// severityNames returns the names incidents can be declared with, the worst first
+func (b *Bot) severityNames() []string {
+	var names []string
+	for _, s := range b.conf.IncidentSeverities() {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// pageIncident messages whoever is on call for the tiers the severity pages
+// and returns their Slack user IDs
+func (b *Bot) pageIncident(inc *incident, policy SeverityConfig) []string {
+	var paged []string
+	for _, tier := range b.Tiers {
+		if !contains(policy.Page, tier.Name) {
+			continue
+		}
+		user, err := b.getOnCallUser(inc.Team, tier, b.teamNow(inc.Team))
+		if err != nil && err != rota.ErrNoShift {
+			b.Logger.Error(fmt.Sprintf("%s rota user retrieval error %v", tier.Name, err))
+		}
+		if user == nil || contains(paged, user.ID) {
+			continue
+		}
+		b.notify(user.ID, fmt.Sprintf(":rotating_light: You're paged as %s for %s, join <#%s>", tier.Name, inc.name(), inc.Channel))
+		inc.log("", fmt.Sprintf("Paged <@%s> as %s", user.ID, tier.Name))
+		paged = append(paged, user.ID)
+	}
+	return paged
+}
+
+// IncidentUpdate answers "incident update <text>" in an incident's channel by
+// posting the status update to the channels the severity notifies
+func (b *Bot) IncidentUpdate(message joe.Message, text string) error {
+	inc, ok, err := b.incidentForChannel(message.Channel)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		message.Respond("Status updates are posted from the incident's channel")
+		return nil
+	}
+	if !inc.Resolved.IsZero() {
+		message.Respond("%s is resolved already", inc.ID)
+		return nil
+	}
+
+	inc.LastUpdate = time.Now()
+	inc.log(message.AuthorID, "Update: "+text)
+	if err := b.saveIncident(inc); err != nil {
+		return err
+	}
+
+	policy, _ := b.conf.IncidentSeverity(inc.Severity)
+	for _, channelID := range policy.Notify {
+		b.notify(channelID, fmt.Sprintf(":information_source: *%s* update from <@%s>: %s", inc.name(), message.AuthorID, text))
+	}
+	if len(policy.Notify) > 0 {
+		message.Respond("Thanks, I've passed the update on")
+	}
+	return nil
+}
+
+// RemindAboutIncidentUpdate reminds the commander of an open incident when
+// the severity's status update is due and checks again after the interval
+func (b *Bot) RemindAboutIncidentUpdate(id string) {
+	inc, ok, err := b.loadIncident(id)
+	if err != nil {
+		b.Logger.Error(err.Error())
+		return
+	}
+	if !ok || !inc.Resolved.IsZero() {
+		return
+	}
+	policy, _ := b.conf.IncidentSeverity(inc.Severity)
+	interval := policy.UpdateInterval()
+	if interval == 0 {
+		return
+	}
+
+	if due := inc.lastUpdate().Add(interval); time.Now().Before(due) {
+		b.scheduleIncidentUpdateReminder(inc.ID, time.Until(due))
+		return
+	}
+	b.notify(inc.Channel, fmt.Sprintf(":hourglass: %s, %s incidents need a status update every %s and the last one was %s ago: @%s incident update <what's happening>",
+		mentionOrNobody(inc.Roles[incidentCommander]), inc.Severity, formatDuration(interval),
+		formatDuration(time.Since(inc.lastUpdate())), b.Bot.Name))
+	b.scheduleIncidentUpdateReminder(inc.ID, interval)
+}
+
+// scheduleIncidentUpdateReminder emits the update event of the incident after the delay
+func (b *Bot) scheduleIncidentUpdateReminder(id string, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		b.Brain.Emit(IncidentUpdateEvent{ID: id})
+	})
+}
+
+// createIncidentPostmortem creates the postmortem of the incident, linked to
+// it and due as the severity requires, and announces it in the incident's channel
+func (b *Bot) createIncidentPostmortem(inc incident, title, actor string) (postmortemRecord, error) {
+	fields := map[string]string{
+		postmortem.FieldIncident: inc.ID,
+		postmortem.FieldSeverity: inc.Severity,
+	}
+	if policy, ok := b.conf.IncidentSeverity(inc.Severity); ok && policy.Postmortem {
+		fields[postmortem.FieldDue] = fmt.Sprintf("%d business days after the incident is resolved", policy.PostmortemDueDays())
+		if !inc.DueBy.IsZero() {
+			fields[postmortem.FieldDue] = inc.DueBy.Format("Mon 2 Jan 2006")
+		}
+	}
+
+	team, _ := b.conf.Team(inc.Team)
+	record, err := b.createPostmortem(team, title, inc.Channel, actor, fields)
+	if err != nil {
+		return record, err
+	}
+
+	inc.Postmortem = record.ID
+	inc.log(actor, fmt.Sprintf("Created the <%s|postmortem>", record.URL))
+	if err := b.saveIncident(inc); err != nil {
+		return record, err
+	}
+	b.updateIncidentSummary(inc)
+	b.notify(inc.Channel, fmt.Sprintf("<@%s> created the postmortem <%s|here>", actor, record.URL))
+	return record, nil
+}
+
+// DailyRemindAboutPostmortems tells the team about mandatory postmortems of
+// its incidents that are due by the next business day or overdue
+func (b *Bot) DailyRemindAboutPostmortems(team string) {
+	all, err := b.incidents()
+	if err != nil {
+		b.Logger.Error(err.Error())
+		return
+	}
+
+	now := time.Now()
+	soon := b.businessDaysAfter(team, now, 1)
+	var lines []string
+	for _, inc := range all {
+		if inc.Team != team || inc.DueBy.IsZero() || inc.DueBy.After(soon) {
+			continue
+		}
+
+		what := fmt.Sprintf("%s hasn't got a postmortem", inc.name())
+		if inc.Postmortem != "" {
+			record, ok, err := b.loadPostmortem(inc.Postmortem)
+			if err != nil {
+				b.Logger.Error(err.Error())
+				continue
+			}
+			if ok && (record.Status == postmortemPublished || record.Status == postmortemClosed) {
+				continue
+			}
+			if ok {
+				what = fmt.Sprintf("The postmortem <%s|%s> is %s", record.URL, record.Title, postmortemStatuses[record.Status])
+			}
+		}
+
+		due := fmt.Sprintf("due by %s", inc.DueBy.Format("Mon 2 Jan"))
+		if now.After(inc.DueBy) {
+			due = fmt.Sprintf("*overdue* since %s", inc.DueBy.Format("Mon 2 Jan"))
+		}
+		lines = append(lines, fmt.Sprintf("• %s, %s (%s)", what, due, mentionOrNobody(inc.Roles[incidentCommander])))
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	text := ":memo: Postmortems needing attention:\n" + strings.Join(lines, "\n")
+	if teamConf, ok := b.conf.Team(team); ok && teamConf.QuestionsChannel != "" {
+		b.notify(teamConf.QuestionsChannel, text)
+		return
+	}
+	level1, err := b.getRotaLevel1(team)
+	if err != nil && err != rota.ErrNoShift {
+		b.Logger.Error(fmt.Sprintf("level 1 rota user retrieval error %v", err))
+	}
+	if level1 != nil {
+		b.notify(level1.ID, text)
+	}
+}
+
+// businessDaysAfter returns the time the number of business days after from,
+// skipping weekends and the team's public holidays
+func (b *Bot) businessDaysAfter(team string, from time.Time, days int) time.Time {
+	loc := time.Local
+	if teamConf, ok := b.conf.Team(team); ok {
+		loc = teamConf.Location()
+	}
+
+	at := from.In(loc)
+	for days > 0 {
+		at = at.AddDate(0, 0, 1)
+		if at.Weekday() == time.Saturday || at.Weekday() == time.Sunday || b.publicHoliday(team, at) != nil {
+			continue
+		}
+		days--
+	}
+	return at
+}
+
+// formatSeverityPolicy renders what the severity of an incident asks for
+func formatSeverityPolicy(policy SeverityConfig) string {
+	lines := []string{fmt.Sprintf("*Severity* %s", policy.Name)}
+	if policy.Description != "" {
+		lines[0] += ", " + policy.Description
+	}
+	if interval := policy.UpdateInterval(); interval > 0 {
+		lines = append(lines, fmt.Sprintf("*Status updates* every %s", formatDuration(interval)))
+	}
+	if policy.Postmortem {
+		lines = append(lines, fmt.Sprintf("*Postmortem* mandatory, due %d business days after resolving", policy.PostmortemDueDays()))
+	}
+	return strings.Join(lines, "\n")
+}
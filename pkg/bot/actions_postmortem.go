@@ -0,0 +1,355 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dombo/hiberBot/pkg/bot/postmortem"
+	"github.com/go-joe/joe"
+)
+
+// Postmortem statuses, a postmortem starts as a draft
+const (
+	postmortemDraft     = "draft"
+	postmortemInReview  = "in-review"
+	postmortemPublished = "published"
+	postmortemClosed    = "closed"
+)
+
+// postmortemStatuses are the names the statuses have in the documents
+var postmortemStatuses = map[string]string{
+	postmortemDraft:     "Draft",
+	postmortemInReview:  "In Review",
+	postmortemPublished: "Published",
+	postmortemClosed:    "Closed",
+}
+
+// postmortemTransitions are the statuses a postmortem can move to from each status
+var postmortemTransitions = map[string][]string{
+	postmortemDraft:     {postmortemInReview, postmortemClosed},
+	postmortemInReview:  {postmortemDraft, postmortemPublished, postmortemClosed},
+	postmortemPublished: {postmortemInReview, postmortemClosed},
+	postmortemClosed:    {postmortemDraft},
+}
+
+// postmortemRecord tracks the lifecycle of a postmortem next to its document
+type postmortemRecord struct {
+	ID          string
+	Title       string
+	URL         string
+	Team        string
+	Channel     string // where status changes are posted
+	Status      string
+	Reviewer    string // Slack user ID, needed to review and publish
+	Transitions []postmortemTransition
+}
+
+type postmortemTransition struct {
+	From  string
+	To    string
+	Actor string // Slack user ID
+	At    time.Time
+}
+
+func postmortemKey(id string) string {
+	return "postmortem." + id
+}
+
+// Postmortem answers "postmortem <title>" by creating a postmortem from the
+// team's template, "postmortem list" with the latest postmortems and the
+// status and reviewer commands
+func (b *Bot) Postmortem(message joe.Message) error {
+	args := strings.TrimSpace(strings.TrimPrefix(message.Text, "postmortem"))
+	if args == "" {
+		message.Respond("You must provide a title for your postmortem: @%s postmortem Service outage", b.Bot.Name)
+		return nil
+	}
+	if b.Postmortems == nil {
+		message.Respond("Postmortems need Google Docs and Drive credentials, which aren't configured")
+		return nil
+	}
+
+	fields := strings.Fields(args)
+	switch strings.ToLower(fields[0]) {
+	case "list":
+		if len(fields) == 1 {
+			return b.ListPostmortems(message)
+		}
+	case "status":
+		if len(fields) == 2 || len(fields) == 3 {
+			return b.PostmortemStatus(message, fields[1:])
+		}
+	case "reviewer":
+		if len(fields) == 3 {
+			return b.PostmortemReviewer(message, fields[1], fields[2])
+		}
+	}
+	return b.CreatePostmortem(message, args)
+}
+
+// CreatePostmortem creates a postmortem with the title in the configured
+// store, in an incident's channel it becomes the postmortem of the incident
+func (b *Bot) CreatePostmortem(message joe.Message, title string) error {
+	var record postmortemRecord
+	inc, ok, err := b.incidentForChannel(message.Channel)
+	if err != nil {
+		return err
+	}
+	if ok && inc.Postmortem != "" {
+		message.Respond("%s has a postmortem already, try @%s postmortem status %s", inc.ID, b.Bot.Name, inc.Postmortem)
+		return nil
+	}
+	if ok {
+		record, err = b.createIncidentPostmortem(inc, title, message.AuthorID)
+	} else {
+		record, err = b.createPostmortem(b.conf.TeamForChannel(message.Channel), title, message.Channel, message.AuthorID, nil)
+	}
+	if err != nil {
+		b.Logger.Error(err.Error())
+		message.Respond("Sorry, I failed to create the postmortem")
+		return nil
+	}
+
+	message.Respond("I've created a postmortem <%s|here>", record.URL)
+	return nil
+}
+
+// createPostmortem creates a postmortem of the team whose status changes are
+// posted to the channel, fields adds to the fields filled in from the template
+func (b *Bot) createPostmortem(team TeamConfig, title, channel, actor string, fields map[string]string) (postmortemRecord, error) {
+	values := map[string]string{
+		postmortem.FieldTitle:    title,
+		postmortem.FieldDate:     time.Now().Format(time.RFC1123),
+		postmortem.FieldStatus:   postmortemStatuses[postmortemDraft],
+		postmortem.FieldTeam:     team.Name,
+		postmortem.FieldReviewer: "Unassigned",
+	}
+	for k, v := range fields {
+		values[k] = v
+	}
+
+	p, err := b.Postmortems.Create(b.conf.PostmortemTemplate(team), values)
+	if err != nil {
+		return postmortemRecord{}, fmt.Errorf("failed to create postmortem %v", err)
+	}
+
+	record := postmortemRecord{
+		ID:          p.ID,
+		Title:       title,
+		URL:         p.URL,
+		Team:        team.Name,
+		Channel:     channel,
+		Status:      postmortemDraft,
+		Transitions: []postmortemTransition{{To: postmortemDraft, Actor: actor, At: time.Now()}},
+	}
+	if err := b.Store.Set(postmortemKey(p.ID), record); err != nil {
+		b.Logger.Error(fmt.Sprintf("failed to store postmortem %s %v", p.ID, err))
+	}
+
+	b.Logger.Info("Successfully created postmortem")
+	b.recordForHandover(team.Name, "Postmortem", fmt.Sprintf("<%s|%s>", p.URL, title))
+	return record, nil
+}
+
+// ListPostmortems responds with the latest postmortems
+func (b *Bot) ListPostmortems(message joe.Message) error {
+	all, err := b.Postmortems.List()
+	if err != nil {
+		return err
+	}
+	if len(all) == 0 {
+		message.Respond("There are no postmortems yet")
+		return nil
+	}
+
+	if len(all) > 10 {
+		all = all[:10]
+	}
+	lines := []string{"The latest postmortems:"}
+	for _, p := range all {
+		lines = append(lines, formatPostmortem(p))
+	}
+	message.Respond("%s", strings.Join(lines, "\n"))
+	return nil
+}
+
+// formatPostmortem renders a postmortem as a line of a list
+func formatPostmortem(p postmortem.Postmortem) string {
+	line := fmt.Sprintf("• %s <%s|%s>", p.Created.Format("Mon 2 Jan"), p.URL, p.Title)
+	if status := p.Fields[postmortem.FieldStatus]; status != "" {
+		line += fmt.Sprintf(" (%s)", status)
+	}
+	return line + fmt.Sprintf(" `%s`", p.ID)
+}
+
+// PostmortemStatus answers "postmortem status <id>" with the status and
+// transitions of a postmortem and "postmortem status <id> <status>" by moving
+// it to the status
+func (b *Bot) PostmortemStatus(message joe.Message, args []string) error {
+	record, ok, err := b.loadPostmortem(args[0])
+	if err != nil {
+		return err
+	}
+	if !ok {
+		message.Respond("I don't know the postmortem %s, try @%s postmortem list", args[0], b.Bot.Name)
+		return nil
+	}
+
+	if len(args) == 1 {
+		message.Respond("%s", formatPostmortemRecord(record))
+		return nil
+	}
+
+	to := strings.ToLower(args[1])
+	if _, ok := postmortemStatuses[to]; !ok {
+		message.Respond("Try @%s postmortem status <id> %s", b.Bot.Name, strings.Join(postmortemStatusNames(), "|"))
+		return nil
+	}
+	if to == record.Status {
+		message.Respond("The postmortem is %s already", postmortemStatuses[to])
+		return nil
+	}
+	if !allowedPostmortemTransition(record.Status, to) {
+		message.Respond("A postmortem can't go from %s to %s, it can go to %s", postmortemStatuses[record.Status],
+			postmortemStatuses[to], strings.Join(postmortemStatusNamesOf(postmortemTransitions[record.Status]), " or "))
+		return nil
+	}
+	if (to == postmortemInReview || to == postmortemPublished) && record.Reviewer == "" {
+		message.Respond("The postmortem needs a reviewer first: @%s postmortem reviewer %s @someone", b.Bot.Name, record.ID)
+		return nil
+	}
+
+	if err := b.Postmortems.Update(record.ID, map[string]string{postmortem.FieldStatus: postmortemStatuses[to]}); err != nil {
+		b.Logger.Error(fmt.Sprintf("failed to update postmortem %s %v", record.ID, err))
+		message.Respond("Sorry, I failed to update the postmortem")
+		return nil
+	}
+
+	from := record.Status
+	record.Status = to
+	record.Transitions = append(record.Transitions, postmortemTransition{From: from, To: to, Actor: message.AuthorID, At: time.Now()})
+	if err := b.Store.Set(postmortemKey(record.ID), record); err != nil {
+		return fmt.Errorf("failed to store postmortem %s %v", record.ID, err)
+	}
+
+	b.postmortemUpdate(message, record, fmt.Sprintf("<@%s> moved the postmortem <%s|%s> from %s to %s",
+		message.AuthorID, record.URL, record.Title, postmortemStatuses[from], postmortemStatuses[to]))
+	return nil
+}
+
+// PostmortemReviewer answers "postmortem reviewer <id> @user" by assigning the reviewer
+func (b *Bot) PostmortemReviewer(message joe.Message, id, mention string) error {
+	match := mentionPattern.FindStringSubmatch(mention)
+	if match == nil {
+		message.Respond("Try @%s postmortem reviewer <id> @someone", b.Bot.Name)
+		return nil
+	}
+
+	record, ok, err := b.loadPostmortem(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		message.Respond("I don't know the postmortem %s, try @%s postmortem list", id, b.Bot.Name)
+		return nil
+	}
+
+	name := match[1]
+	if who, err := b.Identities.ForUser(match[1]); err == nil && who.User != nil {
+		name = who.User.RealName
+		if name == "" {
+			name = who.User.Name
+		}
+	}
+	if err := b.Postmortems.Update(record.ID, map[string]string{postmortem.FieldReviewer: name}); err != nil {
+		b.Logger.Error(fmt.Sprintf("failed to update postmortem %s %v", record.ID, err))
+		message.Respond("Sorry, I failed to update the postmortem")
+		return nil
+	}
+
+	record.Reviewer = match[1]
+	if err := b.Store.Set(postmortemKey(record.ID), record); err != nil {
+		return fmt.Errorf("failed to store postmortem %s %v", record.ID, err)
+	}
+
+	b.postmortemUpdate(message, record, fmt.Sprintf("<@%s> is reviewing the postmortem <%s|%s>",
+		record.Reviewer, record.URL, record.Title))
+	return nil
+}
+
+// loadPostmortem returns the record of the postmortem, postmortems that were
+// created before they were tracked get a record from their document
+func (b *Bot) loadPostmortem(id string) (postmortemRecord, bool, error) {
+	var record postmortemRecord
+	ok, err := b.Store.Get(postmortemKey(id), &record)
+	if err != nil {
+		return record, false, fmt.Errorf("failed to load postmortem %s %v", id, err)
+	}
+	if ok {
+		return record, true, nil
+	}
+
+	p, err := postmortem.Get(b.Postmortems, id)
+	if err != nil {
+		return record, false, nil
+	}
+	record = postmortemRecord{ID: p.ID, Title: p.Title, URL: p.URL, Team: p.Fields[postmortem.FieldTeam], Status: postmortemDraft}
+	for status, name := range postmortemStatuses {
+		if strings.EqualFold(p.Fields[postmortem.FieldStatus], name) {
+			record.Status = status
+		}
+	}
+	return record, true, nil
+}
+
+// postmortemUpdate posts a change of the postmortem to its channel and to
+// whoever made it, when they are somewhere else
+func (b *Bot) postmortemUpdate(message joe.Message, record postmortemRecord, text string) {
+	if record.Channel != "" {
+		b.notify(record.Channel, text)
+	}
+	if record.Channel != message.Channel {
+		message.Respond("%s", text)
+	}
+}
+
+func allowedPostmortemTransition(from, to string) bool {
+	for _, s := range postmortemTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+func postmortemStatusNames() []string {
+	return []string{postmortemDraft, postmortemInReview, postmortemPublished, postmortemClosed}
+}
+
+func postmortemStatusNamesOf(statuses []string) []string {
+	names := make([]string, 0, len(statuses))
+	for _, s := range statuses {
+		names = append(names, postmortemStatuses[s])
+	}
+	return names
+}
+
+// formatPostmortemRecord renders the status of a postmortem and how it got there
+func formatPostmortemRecord(record postmortemRecord) string {
+	reviewer := "nobody"
+	if record.Reviewer != "" {
+		reviewer = fmt.Sprintf("<@%s>", record.Reviewer)
+	}
+	lines := []string{fmt.Sprintf("<%s|%s> is %s, reviewed by %s",
+		record.URL, record.Title, postmortemStatuses[record.Status], reviewer)}
+	for _, t := range record.Transitions {
+		if t.From == "" {
+			lines = append(lines, fmt.Sprintf("• %s created by <@%s>", t.At.Format("Mon 2 Jan 15:04"), t.Actor))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("• %s %s → %s by <@%s>", t.At.Format("Mon 2 Jan 15:04"),
+			postmortemStatuses[t.From], postmortemStatuses[t.To], t.Actor))
+	}
+	return strings.Join(lines, "\n")
+}
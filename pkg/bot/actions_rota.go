@@ -1,237 +1,285 @@
 package bot
 
 import (
-	"bytes"
 	"fmt"
-	"google.golang.org/api/docs/v1"
-	"google.golang.org/api/drive/v3"
+	"github.com/dombo/hiberBot/pkg/bot/rota"
+	"sort"
+	"strconv"
 	"strings"
-	"text/template"
 	"time"
 
 	"github.com/go-joe/joe"
 	"github.com/jinzhu/now"
 	slackAPI "github.com/slack-go/slack"
-	"github.com/spf13/viper"
 )
 
-func (b *Bot) Postmortem(message joe.Message) error {
-	requestedPostmortemTitle := strings.TrimSpace(strings.TrimPrefix(message.Text, "postmortem"))
-	if requestedPostmortemTitle == "" {
-		message.Respond("You must provide a title for your postmortem: @%s postmortem Service outage", b.Bot.Name)
-		return nil
+func (b *Bot) DailySendLevel1TheRunbook(team string) {
+	rotas, err := b.getRota(team, b.teamNow(team))
+	if err != nil {
+		b.Logger.Error(err.Error())
+		return
 	}
 
-	var postmortemNameTemplate = template.Must(
-		template.New("").
-			Parse(`{{.PostmortemDate}}.{{.PostmortemTitle}}.Postmortem`))
-
-	var tpl bytes.Buffer
-	err := postmortemNameTemplate.Execute(&tpl, struct {
-		PostmortemDate  string
-		PostmortemTitle string
-	}{
-		PostmortemDate:  time.Now().Format("2006-01-02"),
-		PostmortemTitle: strings.Replace(requestedPostmortemTitle," ", "-", -1),
-	})
-	if err != nil {
-		b.Logger.Error(fmt.Sprintf("failed to template postmortem title %v", err))
+	level1 := rotas[0]
+	if level1.User == nil {
+		b.Logger.Error(fmt.Sprintf("nobody is on %s to send the runbook to", level1.Tier.Name))
+		return
 	}
 
-	driveService := drive.NewFilesService(b.Drive)
+	b.sendMessage(MessageRunbook, team, level1.User.ID)
+}
 
-	tmpl, err := driveService.Get(viper.GetString("google.drive.postmortem_file_id")).Do()
-	if err != nil {
-		b.Logger.Error(fmt.Sprintf("failed to get postmortem template %v", err))
+// GetRota answers "rota [team] [when]", "rota my shifts [weeks]" and the rota change commands
+func (b *Bot) GetRota(message joe.Message) error {
+	args := strings.TrimSpace(strings.TrimPrefix(message.Text, "rota"))
+	switch lower := strings.ToLower(args); {
+	case strings.HasPrefix(lower, "my shifts"):
+		return b.GetMyShifts(message, strings.TrimSpace(args[len("my shifts"):]))
+	case strings.HasPrefix(lower, "swap "), strings.HasPrefix(lower, "cover "):
+		return b.RequestRotaChange(message, args)
+	case strings.HasPrefix(lower, "generate "):
+		return b.GenerateRota(message, args)
+	case lower == "stats" || strings.HasPrefix(lower, "stats "):
+		return b.GetRotaStats(message, strings.TrimSpace(args[len("stats"):]))
+	case strings.HasPrefix(lower, "check"):
+		return b.GetRotaProblems(message, strings.TrimSpace(args[len("check"):]))
+	case lower == "away" || strings.HasPrefix(lower, "away "):
+		return b.GetRotaAbsences(message, strings.TrimSpace(args[len("away"):]))
 	}
 
-	md := drive.File{
-		Name: tpl.String(),
+	team := b.conf.TeamForChannel(message.Channel)
+	if fields := strings.Fields(args); len(fields) > 0 {
+		if t, ok := b.conf.Team(fields[0]); ok {
+			team = t
+			args = strings.Join(fields[1:], " ")
+		}
 	}
 
-	newPostmortem, err := driveService.Copy(tmpl.Id, &md).Do()
+	ref := b.teamNow(team.Name)
+	period, err := rota.ParsePeriod(args, ref)
 	if err != nil {
-		b.Logger.Error(fmt.Sprintf("failed to create file %v", err))
-	}
-
-	docsService := docs.NewDocumentsService(b.Docs)
-	docsRequests := make([]*docs.Request, 0)
-	docsRequests = append(docsRequests, &docs.Request{
-		ReplaceAllText: &docs.ReplaceAllTextRequest{
-			ContainsText: &docs.SubstringMatchCriteria{
-				MatchCase: false,
-				Text:      "{{title}}",
-			},
-			ReplaceText: requestedPostmortemTitle,
-		},
-	})
-	docsRequests = append(docsRequests, &docs.Request{
-		ReplaceAllText: &docs.ReplaceAllTextRequest{
-			ContainsText: &docs.SubstringMatchCriteria{
-				MatchCase: false,
-				Text:      "{{date}}",
-			},
-			ReplaceText: time.Now().Format(time.RFC1123),
-		},
-	})
-	docsRequests = append(docsRequests, &docs.Request{
-		ReplaceAllText: &docs.ReplaceAllTextRequest{
-			ContainsText: &docs.SubstringMatchCriteria{
-				MatchCase: false,
-				Text:      "{{status}}",
-			},
-			ReplaceText: "In Progress",
-		},
-	})
-	req := docs.BatchUpdateDocumentRequest{
-		Requests: docsRequests,
-	}
-	updatedRequest, err := docsService.BatchUpdate(newPostmortem.Id, &req).Do()
+		message.Respond("%v. Try @%s rota [%s] [tomorrow|friday|next week|2020-06-18|monday to friday]",
+			err, b.Bot.Name, strings.Join(b.teamNames(), "|"))
+		return nil
+	}
+
+	var text string
+	if period.Single {
+		text, err = b.formatRotaAt(team.Name, period.From, ref)
+	} else {
+		text, err = b.formatRotaBetween(team.Name, period.From, period.To)
+	}
 	if err != nil {
-		b.Logger.Error(fmt.Sprintf("Failed to update the postmortem text %v", err))
+		return err
 	}
 
-	if updatedRequest.HTTPStatusCode == 200 {
-		b.Logger.Info("Successfully created postmortem")
-		_, _, err := b.Slack.PostMessage(message.Channel,
-			slackAPI.MsgOptionText(
-				fmt.Sprintf("I've created a postmortem <https://docs.google.com/document/d/%s/edit#|here>",
-					updatedRequest.DocumentId), false),
-		)
-		if err != nil {
-			fmt.Printf("%s\n", err)
-			return nil
-		}
+	if len(b.conf.Teams) > 1 {
+		text = fmt.Sprintf("For %s %s", team.Name, text)
 	}
+	message.Respond("%s", text)
 
 	return nil
 }
 
-func (b *Bot) DailySendLevel1TheRunbook() {
-	level1, err := b.getRotaLevel1()
-	if err != nil {
-		b.Logger.Error(fmt.Sprintf("level 1 rota user retrieval error %v", err))
+// GetMyShifts lists the upcoming shifts of the person asking across all teams
+func (b *Bot) GetMyShifts(message joe.Message, args string) error {
+	weeks := 4
+	if fields := strings.Fields(args); len(fields) > 0 {
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 1 {
+			message.Respond("Try @%s rota my shifts [number of weeks]", b.Bot.Name)
+			return nil
+		}
+		weeks = n
 	}
 
-	channelID, timestamp, err := b.Slack.PostMessage(level1.ID, slackAPI.MsgOptionText("", false)) // TODO Format this with some relevant text
+	me, err := b.Identities.ForUser(message.AuthorID)
 	if err != nil {
-		b.Logger.Error(fmt.Sprintf("error sending daily runbook message %v", err))
+		return err
 	}
-	b.Logger.Info(fmt.Sprintf("Message successfully sent to channel %s at %s", channelID, timestamp))
-}
 
-func (b *Bot) DailySendLevel1TheSignoffReminder() {
-	level1, err := b.getRotaLevel1()
-	if err != nil {
-		b.Logger.Error(fmt.Sprintf("level 1 rota user retrieval error %v", err))
+	from := now.BeginningOfDay()
+	to := from.AddDate(0, 0, 7*weeks)
+
+	var mine []rota.Shift
+	for _, team := range b.conf.RotaTeams() {
+		shifts, err := b.Rota.Shifts(team.Name, from, to)
+		if err != nil {
+			return fmt.Errorf("failed to list shifts of %s %v", team.Name, err)
+		}
+		for _, s := range shifts {
+			if b.Identities.Same(s.Email, me.Email) {
+				mine = append(mine, s)
+			}
+		}
 	}
 
-	channelID, timestamp, err := b.Slack.PostMessage(level1.ID, slackAPI.MsgOptionText("", false)) // TODO Format this with some relevant text
-	if err != nil {
-		b.Logger.Error(fmt.Sprintf("error sending daily signoff reminder %v", err))
+	if len(mine) == 0 {
+		message.Respond("You have no shifts in the next %d weeks", weeks)
+		return nil
+	}
+
+	sort.Slice(mine, func(i, j int) bool { return mine[i].Start.Before(mine[j].Start) })
+	lines := make([]string, 0, len(mine))
+	for _, s := range mine {
+		lines = append(lines, fmt.Sprintf("• %s %s %s", s.Team, s.Tier, formatShiftSpan(s)))
 	}
-	b.Logger.Info(fmt.Sprintf("Message successfully sent to channel %s at %s", channelID, timestamp))
+	message.Respond("Your shifts in the next %d weeks:\n%s", weeks, strings.Join(lines, "\n"))
+
+	return nil
 }
 
-func (b *Bot) DailySendLevel1TheCongratulationsMessage() {
-	level1, err := b.getRotaLevel1()
+// formatRotaAt renders who covers every tier of the team on the day of at
+func (b *Bot) formatRotaAt(team string, at, ref time.Time) (string, error) {
+	rotas, err := b.getRota(team, at)
 	if err != nil {
-		b.Logger.Error(fmt.Sprintf("level 1 rota user retrieval error %v", err))
+		return "", err
 	}
 
-	channelID, timestamp, err := b.Slack.PostMessage(level1.ID, slackAPI.MsgOptionText("", false)) // TODO Format this with some relevant text
-	if err != nil {
-		b.Logger.Error(fmt.Sprintf("error sending daily congratulations message %v", err))
+	parts := make([]string, 0, len(rotas))
+	for _, r := range rotas {
+		parts = append(parts, fmt.Sprintf("%s is on %s %s", r.Name(), r.Tier.Name, r.Tier.Window.DescribeAt(at, ref)))
 	}
-	b.Logger.Info(fmt.Sprintf("Message successfully sent to channel %s at %s", channelID, timestamp))
+	return joinAnd(parts) + "!", nil
 }
 
-func (b *Bot) GetTodaysRota(message joe.Message) error {
-	level1, err := b.getRotaLevel1()
+// formatRotaBetween lists every shift of the team between from and to
+func (b *Bot) formatRotaBetween(team string, from, to time.Time) (string, error) {
+	shifts, err := b.Rota.Shifts(team, from, to)
 	if err != nil {
-		return fmt.Errorf("level 1 rota user retrieval error %v", err)
-	}
-	level2, err := b.getRotaLevel2()
-	if err != nil {
-		return fmt.Errorf("level 2 rota user retrieval error %v", err)
+		return "", err
 	}
 
-	message.Respond("%s is on Level 1 today and %s is on Level 2 this week!", level1.Name, level2.Name)
+	header := fmt.Sprintf("from %s to %s:", from.Format("Mon 2 Jan"), to.Format("Mon 2 Jan"))
+	if len(shifts) == 0 {
+		return header + " nobody is on call", nil
+	}
 
-	return nil
+	sort.SliceStable(shifts, func(i, j int) bool { return shifts[i].Start.Before(shifts[j].Start) })
+	names := map[string]string{}
+	lines := []string{header}
+	for _, s := range shifts {
+		if _, ok := names[s.Email]; !ok {
+			names[s.Email] = b.slackNameForEmail(s.Email)
+		}
+		lines = append(lines, fmt.Sprintf("• %s %s %s", s.Tier, names[s.Email], formatShiftSpan(s)))
+	}
+	return strings.Join(lines, "\n"), nil
 }
 
-// Set the questions channel with level 1 and level 2 on-call user details
-func (b *Bot) DailySetQuestionsChannelTopic() {
-	level1, err := b.getRotaLevel1()
+// slackNameForEmail returns the Slack name of the person with the email, or the email itself
+func (b *Bot) slackNameForEmail(email string) string {
+	user, err := b.Identities.User(email)
 	if err != nil {
-		b.Logger.Error(fmt.Sprintf("level 1 rota user retrieval error %v", err))
+		return email
 	}
-	level2, err := b.getRotaLevel2()
-	if err != nil {
-		b.Logger.Error(fmt.Sprintf("level 2 rota user retrieval error %v", err))
+	return user.Name
+}
+
+// Set the team's questions channel with the on-call user details of every tier
+func (b *Bot) DailySetQuestionsChannelTopic(team string) {
+	teamConf, ok := b.conf.Team(team)
+	if !ok || teamConf.QuestionsChannel == "" {
+		b.Logger.Info(fmt.Sprintf("no questions channel configured for team %s", team))
+		return
 	}
 
-	topicText := fmt.Sprintf(
-		`L1: %s
-		L2: %s`,
-		level1.Name, level2.Name)
+	rotas, err := b.getRota(team, b.teamNow(team))
+	if err != nil {
+		b.Logger.Error(err.Error())
+		return
+	}
 
-	topic, err := b.Slack.SetChannelTopic("C0159JKU1NW", topicText) // TODO Pull channel ID out to a conf
+	channel, err := b.Slack.SetTopicOfConversation(teamConf.QuestionsChannel, formatRotaLines(rotas))
 	if err != nil {
 		b.Logger.Error(fmt.Sprintf("topic setting error %v", err))
+		return
 	}
-	b.Logger.Info(fmt.Sprintf("topic set to: %s", topic))
+	b.Logger.Info(fmt.Sprintf("topic set to: %s", channel.Topic.Value))
 }
 
-func (b *Bot) getRotaLevel2() (*slackAPI.User, error) {
-	weeksRota, err := b.Calendar.Events.
-		List(viper.GetString("calendar.rota_calendar_id")).
-		TimeMin(now.BeginningOfWeek().Format(time.RFC3339)).
-		TimeMax(now.EndOfWeek().Format(time.RFC3339)).
-		Do()
+// onCall pairs a tier with the Slack user covering it, User is nil when nobody is
+type onCall struct {
+	Tier rota.Tier
+	User *slackAPI.User
+}
 
-	if err != nil {
-		return nil, err
+func (o onCall) Name() string {
+	if o.User == nil {
+		return "nobody"
 	}
+	return o.User.Name
+}
 
-	var level2 string
-	for _, e := range weeksRota.Items {
-		if strings.HasPrefix(e.Summary, "L2:") {
-			level2 = e.Attendees[0].Email
+// getRota looks up who covers each configured tier of the team at the given time
+func (b *Bot) getRota(team string, at time.Time) ([]onCall, error) {
+	rotas := make([]onCall, 0, len(b.Tiers))
+	for _, tier := range b.Tiers {
+		user, err := b.getOnCallUser(team, tier, at)
+		if err != nil && err != rota.ErrNoShift {
+			return nil, fmt.Errorf("%s rota user retrieval error %v", tier.Name, err)
 		}
+		rotas = append(rotas, onCall{Tier: tier, User: user})
 	}
+	return rotas, nil
+}
+
+// getRotaLevel1 looks up who is on the first tier of the team right now
+func (b *Bot) getRotaLevel1(team string) (*slackAPI.User, error) {
+	return b.getOnCallUser(team, b.Tiers[0], b.teamNow(team))
+}
 
-	level2user, err := b.Slack.GetUserByEmail(level2)
+// teamNow returns the current time in the team's time zone. Rota lookups use
+// it so the team's days and weeks begin at the team's midnight, like its
+// scheduled events do.
+func (b *Bot) teamNow(team string) time.Time {
+	teamConf, _ := b.conf.Team(team)
+	return time.Now().In(teamConf.Location())
+}
+
+// getOnCallUser looks up the Slack user that is on call for the team's tier at the given time
+func (b *Bot) getOnCallUser(team string, tier rota.Tier, at time.Time) (*slackAPI.User, error) {
+	shift, err := rota.OnCall(b.Rota, team, tier, at)
 	if err != nil {
 		return nil, err
 	}
 
-	return level2user, nil
+	return b.Identities.User(shift.Email)
 }
 
-func (b *Bot) getRotaLevel1() (*slackAPI.User, error) {
-	weeksRota, err := b.Calendar.Events.
-		List(viper.GetString("calendar.rota_calendar_id")).
-		TimeMin(now.BeginningOfDay().Format(time.RFC3339)).
-		TimeMax(now.EndOfDay().Format(time.RFC3339)).
-		Do()
+func (b *Bot) teamNames() []string {
+	var names []string
+	for _, t := range b.conf.RotaTeams() {
+		names = append(names, t.Name)
+	}
+	return names
+}
 
-	if err != nil {
-		return nil, err
+// formatRotaLines renders one "L1: name" line per tier
+func formatRotaLines(rotas []onCall) string {
+	lines := make([]string, 0, len(rotas))
+	for _, r := range rotas {
+		lines = append(lines, fmt.Sprintf("%s: %s", r.Tier.Name, r.Name()))
 	}
+	return strings.Join(lines, "\n")
+}
 
-	var level1 string
-	for _, e := range weeksRota.Items {
-		if strings.HasPrefix(e.Summary, "L1:") {
-			level1 = e.Attendees[0].Email
+// formatShiftSpan renders when a shift runs, whole days are shown without times
+func formatShiftSpan(s rota.Shift) string {
+	if s.WholeDays() {
+		last := s.End.AddDate(0, 0, -1)
+		if !last.After(s.Start) {
+			return s.Start.Format("Mon 2 Jan")
 		}
+		return fmt.Sprintf("%s – %s", s.Start.Format("Mon 2 Jan"), last.Format("Mon 2 Jan"))
 	}
+	return fmt.Sprintf("%s – %s", s.Start.Format("Mon 2 Jan 15:04"), s.End.Format("Mon 2 Jan 15:04"))
+}
 
-	level1user, err := b.Slack.GetUserByEmail(level1)
-	if err != nil {
-		return nil, err
+// joinAnd joins the parts into a sentence list: "a, b and c"
+func joinAnd(parts []string) string {
+	if len(parts) < 2 {
+		return strings.Join(parts, "")
 	}
-
-	return level1user, nil
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
 }
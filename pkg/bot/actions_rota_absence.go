@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dombo/hiberBot/pkg/bot/rota"
+	"github.com/go-joe/joe"
+	"github.com/jinzhu/now"
+)
+
+// rotaAbsenceKey is the store key remembering that a conflict was flagged
+func rotaAbsenceKey(c rota.Conflict) string {
+	return fmt.Sprintf("rota.absence.%s.%s.%s.%d", c.Shift.Team, c.Shift.Tier, c.Shift.Email, c.Shift.Start.Unix())
+}
+
+// DailyWarnAboutAbsences tells the team lead about upcoming shifts of people
+// who are away according to their own calendar. Every conflict is only
+// flagged once.
+func (b *Bot) DailyWarnAboutAbsences(team string) {
+	teamConf, ok := b.conf.Team(team)
+	if !ok {
+		return
+	}
+
+	channelID := teamConf.QuestionsChannel
+	if teamConf.Lead != "" {
+		lead, err := b.Identities.User(teamConf.Lead)
+		if err != nil {
+			b.Logger.Error(fmt.Sprintf("failed to find team lead %s %v", teamConf.Lead, err))
+		} else {
+			channelID = lead.ID
+		}
+	}
+	if channelID == "" {
+		b.Logger.Info(fmt.Sprintf("no team lead or questions channel configured for team %s", team))
+		return
+	}
+
+	conflicts, err := b.rotaConflicts(team)
+	if err != nil {
+		b.Logger.Error(err.Error())
+		return
+	}
+
+	var fresh []rota.Conflict
+	for _, c := range conflicts {
+		var flagged bool
+		if _, err := b.Store.Get(rotaAbsenceKey(c), &flagged); err != nil {
+			b.Logger.Error(fmt.Sprintf("failed to look up flagged absence %v", err))
+			return
+		}
+		if !flagged {
+			fresh = append(fresh, c)
+		}
+	}
+	if len(fresh) == 0 {
+		b.Logger.Info(fmt.Sprintf("no new absences clash with the %s rota", team))
+		return
+	}
+
+	lines, err := b.formatConflicts(teamConf, fresh)
+	if err != nil {
+		b.Logger.Error(err.Error())
+		return
+	}
+	b.notify(channelID, fmt.Sprintf(":palm_tree: Some people on the %s rota in the next %d days look to be away:\n%s",
+		team, b.conf.RotaAbsence().Days, strings.Join(lines, "\n")))
+
+	for _, c := range fresh {
+		if err := b.Store.Set(rotaAbsenceKey(c), true); err != nil {
+			b.Logger.Error(fmt.Sprintf("failed to remember flagged absence %v", err))
+		}
+	}
+}
+
+// GetRotaAbsences answers "rota away [team]"
+func (b *Bot) GetRotaAbsences(message joe.Message, args string) error {
+	if b.Absences == nil {
+		message.Respond("I can't read people's calendars, Google Calendar credentials aren't configured")
+		return nil
+	}
+
+	team := b.conf.TeamForChannel(message.Channel)
+	if args != "" {
+		var ok bool
+		team, ok = b.conf.Team(args)
+		if !ok {
+			message.Respond("I don't know the team %s, try one of: %s", args, strings.Join(b.teamNames(), ", "))
+			return nil
+		}
+	}
+
+	conflicts, err := b.rotaConflicts(team.Name)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		message.Respond("Nobody on the %s rota is away in the next %d days", team.Name, b.conf.RotaAbsence().Days)
+		return nil
+	}
+
+	lines, err := b.formatConflicts(team, conflicts)
+	if err != nil {
+		return err
+	}
+	message.Respond("Some people on the %s rota in the next %d days look to be away:\n%s",
+		team.Name, b.conf.RotaAbsence().Days, strings.Join(lines, "\n"))
+	return nil
+}
+
+// rotaConflicts returns the upcoming shifts of the team whose person is away
+func (b *Bot) rotaConflicts(team string) ([]rota.Conflict, error) {
+	from := now.With(b.teamNow(team)).BeginningOfDay()
+	to := from.AddDate(0, 0, b.conf.RotaAbsence().Days)
+
+	conflicts, err := rota.Conflicts(b.Rota, b.Absences, team, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the %s rota for absences %v", team, err)
+	}
+	return conflicts, nil
+}
+
+// formatConflicts renders one line per conflict with suggested replacements
+// from the team's roster
+func (b *Bot) formatConflicts(team TeamConfig, conflicts []rota.Conflict) ([]string, error) {
+	members, err := b.conf.RotaMembers(team)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0, len(conflicts))
+	for _, c := range conflicts {
+		line := fmt.Sprintf("• %s is on %s %s but has %q %s",
+			b.slackNameForEmail(c.Shift.Email), c.Shift.Tier, formatShiftSpan(c.Shift),
+			c.Absence.Summary, formatShiftSpan(rota.Shift{Start: c.Absence.Start, End: c.Absence.End}))
+
+		if len(members) > 0 {
+			candidates, err := rota.Replacements(b.Rota, b.Absences, c.Shift, members)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find replacements for %s %v", c.Shift.Email, err)
+			}
+			if len(candidates) > 3 {
+				candidates = candidates[:3]
+			}
+
+			names := make([]string, 0, len(candidates))
+			for _, email := range candidates {
+				names = append(names, b.slackNameForEmail(email))
+			}
+			if len(names) > 0 {
+				line += fmt.Sprintf(", %s could take it", joinAnd(names))
+			} else {
+				line += ", and nobody on the roster is free to take it"
+			}
+		}
+
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
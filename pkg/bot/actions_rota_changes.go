@@ -0,0 +1,225 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dombo/hiberBot/pkg/bot/rota"
+	"github.com/go-joe/joe"
+	slackAPI "github.com/slack-go/slack"
+)
+
+// Block Kit action IDs of the rota change confirmation buttons
+const (
+	actionRotaChangeAccept  = "rota_change_accept"
+	actionRotaChangeDecline = "rota_change_decline"
+)
+
+// mentionPattern matches a Slack user mention such as <@U0123> or <@U0123|alice>
+var mentionPattern = regexp.MustCompile(`^<@([A-Z0-9]+)(?:\|[^>]*)?>$`)
+
+// rotaChange is a swap or cover waiting for the other party to confirm it
+type rotaChange struct {
+	ID        string
+	Kind      string // swap or cover
+	Team      string
+	Requester string // Slack user ID of whoever asked for the change
+	Other     string // Slack user ID of whoever has to confirm it
+	Overrides []rota.Shift
+	Applied   int // overrides already written, an accepted change resumes here when writing failed
+}
+
+func rotaChangeKey(id string) string {
+	return "rota.change." + id
+}
+
+// RequestRotaChange handles "rota swap @alice <when>" and "rota cover @bob <when>".
+// A swap exchanges the shifts both people have in that period, a cover moves
+// the other person's shifts to whoever asked. The change is only written to the
+// rota once the other person accepts it.
+func (b *Bot) RequestRotaChange(message joe.Message, args string) error {
+	fields := strings.Fields(args)
+	usage := fmt.Sprintf("Try @%s rota swap @alice 2020-06-18 or @%s rota cover @bob tomorrow", b.Bot.Name, b.Bot.Name)
+	if len(fields) < 3 {
+		message.Respond(usage)
+		return nil
+	}
+
+	kind := strings.ToLower(fields[0])
+	mention := mentionPattern.FindStringSubmatch(fields[1])
+	if mention == nil {
+		message.Respond(usage)
+		return nil
+	}
+	other := mention[1]
+	if other == message.AuthorID {
+		message.Respond("You can't %s with yourself", kind)
+		return nil
+	}
+
+	writer, ok := b.Rota.(rota.Writer)
+	if !ok {
+		message.Respond("The configured rota can't be changed from Slack, please update it at the source")
+		return nil
+	}
+
+	team := b.conf.TeamForChannel(message.Channel)
+	period, err := rota.ParsePeriod(strings.Join(fields[2:], " "), b.teamNow(team.Name))
+	if err != nil {
+		message.Respond("%v. %s", err, usage)
+		return nil
+	}
+	to := period.To.Add(time.Nanosecond) // the end of a period is inclusive, shifts are not
+
+	requesterEmail, err := b.emailForUser(message.AuthorID)
+	if err != nil {
+		return err
+	}
+	otherEmail, err := b.emailForUser(other)
+	if err != nil {
+		return err
+	}
+
+	shifts, err := writer.Shifts(team.Name, period.From, to)
+	if err != nil {
+		return fmt.Errorf("failed to list shifts of %s %v", team.Name, err)
+	}
+
+	var overrides []rota.Shift
+	for _, s := range shifts {
+		switch {
+		case b.Identities.Same(s.Email, otherEmail):
+			s.Email = requesterEmail
+		case kind == "swap" && b.Identities.Same(s.Email, requesterEmail):
+			s.Email = otherEmail
+		default:
+			continue
+		}
+		overrides = append(overrides, s.Clip(period.From, to))
+	}
+
+	if len(overrides) == 0 {
+		message.Respond("There are no shifts to %s in that period", kind)
+		return nil
+	}
+
+	change := rotaChange{
+		ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
+		Kind:      kind,
+		Team:      team.Name,
+		Requester: message.AuthorID,
+		Other:     other,
+		Overrides: overrides,
+	}
+	if err := b.Store.Set(rotaChangeKey(change.ID), change); err != nil {
+		return fmt.Errorf("failed to store rota change %v", err)
+	}
+
+	text := fmt.Sprintf("<@%s> would like to %s shifts with you:\n%s", change.Requester, kind, b.formatOverrides(overrides))
+	if kind == "cover" {
+		text = fmt.Sprintf("<@%s> offered to cover your shifts:\n%s", change.Requester, b.formatOverrides(overrides))
+	}
+
+	accept := slackAPI.NewButtonBlockElement(actionRotaChangeAccept, change.ID,
+		slackAPI.NewTextBlockObject(slackAPI.PlainTextType, "Accept", false, false))
+	accept.Style = slackAPI.StylePrimary
+	decline := slackAPI.NewButtonBlockElement(actionRotaChangeDecline, change.ID,
+		slackAPI.NewTextBlockObject(slackAPI.PlainTextType, "Decline", false, false))
+	decline.Style = slackAPI.StyleDanger
+
+	_, _, err = b.Slack.PostMessage(other,
+		slackAPI.MsgOptionText(text, false),
+		slackAPI.MsgOptionBlocks(
+			markdownSection(text),
+			slackAPI.NewActionBlock("rota_change", accept, decline),
+		),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to ask <@%s> to confirm the rota change %v", other, err)
+	}
+
+	message.Respond("I've asked <@%s> to confirm", other)
+	return nil
+}
+
+// RotaChangeResponse applies or drops a rota change once the other party clicked a button
+func (b *Bot) RotaChangeResponse(callback slackAPI.InteractionCallback, action *slackAPI.BlockAction) error {
+	var change rotaChange
+	ok, err := b.Store.Get(rotaChangeKey(action.Value), &change)
+	if err != nil {
+		return fmt.Errorf("failed to load rota change %v", err)
+	}
+	if !ok {
+		b.replaceInteractiveMessage(callback, "This rota change has already been handled")
+		return nil
+	}
+	if callback.User.ID != change.Other {
+		return nil
+	}
+
+	if action.ActionID == actionRotaChangeDecline {
+		if _, err := b.Store.Delete(rotaChangeKey(change.ID)); err != nil {
+			return fmt.Errorf("failed to delete rota change %v", err)
+		}
+		b.replaceInteractiveMessage(callback, "You declined the rota change")
+		b.notify(change.Requester, fmt.Sprintf("<@%s> declined your rota %s", change.Other, change.Kind))
+		return nil
+	}
+
+	writer, ok := b.Rota.(rota.Writer)
+	if !ok {
+		return fmt.Errorf("rota provider does not support changes")
+	}
+	for ; change.Applied < len(change.Overrides); change.Applied++ {
+		if err := writer.Override(change.Overrides[change.Applied]); err != nil {
+			// Keeping the change and its buttons, accepting again writes the rest
+			if err := b.Store.Set(rotaChangeKey(change.ID), change); err != nil {
+				b.Logger.Error(fmt.Sprintf("failed to store rota change %v", err))
+			}
+			b.notify(callback.User.ID, "Sorry, I failed to update the rota, click Accept again to retry")
+			return fmt.Errorf("failed to write rota override %v", err)
+		}
+	}
+	if _, err := b.Store.Delete(rotaChangeKey(change.ID)); err != nil {
+		return fmt.Errorf("failed to delete rota change %v", err)
+	}
+
+	summary := b.formatOverrides(change.Overrides)
+	b.replaceInteractiveMessage(callback, "Done! The rota now reads:\n"+summary)
+	b.notify(change.Requester, fmt.Sprintf("<@%s> accepted your rota %s", change.Other, change.Kind))
+
+	if team, ok := b.conf.Team(change.Team); ok && team.QuestionsChannel != "" {
+		b.notify(team.QuestionsChannel, fmt.Sprintf("Rota change: <@%s> and <@%s> agreed to a %s\n%s",
+			change.Requester, change.Other, change.Kind, summary))
+	}
+
+	return nil
+}
+
+// formatOverrides renders one line per changed shift
+func (b *Bot) formatOverrides(overrides []rota.Shift) string {
+	lines := make([]string, 0, len(overrides))
+	for _, s := range overrides {
+		lines = append(lines, fmt.Sprintf("• %s %s now %s", s.Tier, formatShiftSpan(s), b.slackNameForEmail(s.Email)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// emailForUser returns the rota email of a Slack user
+func (b *Bot) emailForUser(userID string) (string, error) {
+	id, err := b.Identities.ForUser(userID)
+	if err != nil {
+		return "", err
+	}
+	return id.Email, nil
+}
+
+// notify posts a plain text message to a channel or user and logs failures
+func (b *Bot) notify(channelID, text string) {
+	_, _, err := b.Slack.PostMessage(channelID, slackAPI.MsgOptionText(text, false))
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("error sending message to %s %v", channelID, err))
+	}
+}
@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dombo/hiberBot/pkg/bot/rota"
+	"github.com/go-joe/joe"
+	"github.com/jinzhu/now"
+)
+
+// DailyWarnAboutRotaProblems posts the problems found in the upcoming rota to the team's questions channel
+func (b *Bot) DailyWarnAboutRotaProblems(team string) {
+	teamConf, ok := b.conf.Team(team)
+	if !ok || teamConf.QuestionsChannel == "" {
+		b.Logger.Info(fmt.Sprintf("no questions channel configured for team %s", team))
+		return
+	}
+
+	problems, err := b.rotaProblems(team)
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("rota check error %v", err))
+		return
+	}
+	if len(problems) == 0 {
+		b.Logger.Info(fmt.Sprintf("no problems found in the %s rota", team))
+		return
+	}
+
+	b.notify(teamConf.QuestionsChannel, fmt.Sprintf(":warning: I found problems with the %s rota in the next %d days:\n%s",
+		team, b.conf.RotaCheck().Days, strings.Join(problems, "\n")))
+}
+
+// GetRotaProblems answers "rota check [team]"
+func (b *Bot) GetRotaProblems(message joe.Message, args string) error {
+	team := b.conf.TeamForChannel(message.Channel)
+	if args != "" {
+		var ok bool
+		team, ok = b.conf.Team(args)
+		if !ok {
+			message.Respond("I don't know the team %s, try one of: %s", args, strings.Join(b.teamNames(), ", "))
+			return nil
+		}
+	}
+
+	problems, err := b.rotaProblems(team.Name)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		message.Respond("The %s rota looks good for the next %d days", team.Name, b.conf.RotaCheck().Days)
+		return nil
+	}
+
+	message.Respond("I found problems with the %s rota in the next %d days:\n%s",
+		team.Name, b.conf.RotaCheck().Days, strings.Join(problems, "\n"))
+	return nil
+}
+
+// rotaProblems checks the team's rota for gaps, overlaps, broken events and
+// people without a Slack user, returning one line per problem
+func (b *Bot) rotaProblems(team string) ([]string, error) {
+	check := b.conf.RotaCheck()
+	from := now.With(b.teamNow(team)).BeginningOfDay()
+	to := from.AddDate(0, 0, check.Days)
+
+	problems, err := rota.Check(b.Rota, team, b.Tiers, from, to, rota.CheckOptions{Weekends: check.Weekends})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the %s rota %v", team, err)
+	}
+
+	lines := make([]string, 0, len(problems))
+	for _, p := range problems {
+		lines = append(lines, "• "+p.Description)
+	}
+
+	shifts, err := b.Rota.Shifts(team, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shifts of %s %v", team, err)
+	}
+
+	checked := map[string]bool{}
+	for _, s := range shifts {
+		if s.Email == "" || checked[s.Email] {
+			continue
+		}
+		checked[s.Email] = true
+
+		if _, err := b.Identities.User(s.Email); err != nil {
+			lines = append(lines, fmt.Sprintf("• %s is on the rota but I can't find them in Slack (%v)", s.Email, err))
+		}
+	}
+
+	return lines, nil
+}
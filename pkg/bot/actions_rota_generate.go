@@ -0,0 +1,259 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dombo/hiberBot/pkg/bot/rota"
+	"github.com/go-joe/joe"
+	slackAPI "github.com/slack-go/slack"
+)
+
+// Block Kit action IDs of the generated rota preview buttons
+const (
+	actionRotaGenerateApprove = "rota_generate_approve"
+	actionRotaGenerateDiscard = "rota_generate_discard"
+)
+
+var generatePattern = regexp.MustCompile(`(?i)^generate\s+(\S+)\s+from\s+(.+?)\s+weeks\s+(\d+)$`)
+
+// maxGenerateWeeks keeps the preview, a block per week, within Slack's 50 blocks a message
+const maxGenerateWeeks = 26
+
+// rotaGeneration is a generated rota waiting to be approved
+type rotaGeneration struct {
+	ID        string
+	Team      string
+	Requester string
+	Shifts    []rota.Shift
+	Written   int // shifts already handled, an approved rota resumes here when writing failed
+}
+
+func rotaGenerationKey(id string) string {
+	return "rota.generation." + id
+}
+
+// GenerateRota handles "rota generate <team> from <date> weeks <n>". It
+// previews a rota built from the team's roster and only writes it to the
+// calendar once someone approves the preview.
+func (b *Bot) GenerateRota(message joe.Message, args string) error {
+	match := generatePattern.FindStringSubmatch(args)
+	if match == nil {
+		message.Respond("Try @%s rota generate <team> from <date> weeks <n>", b.Bot.Name)
+		return nil
+	}
+
+	team, ok := b.conf.Team(match[1])
+	if !ok {
+		message.Respond("I don't know the team %s, try one of: %s", match[1], strings.Join(b.teamNames(), ", "))
+		return nil
+	}
+
+	if _, ok := b.Rota.(rota.Writer); !ok {
+		message.Respond("The configured rota can't be changed from Slack, please update it at the source")
+		return nil
+	}
+
+	period, err := rota.ParsePeriod(match[2], time.Now().In(team.Location()))
+	if err != nil {
+		message.Respond("%v", err)
+		return nil
+	}
+	weeks, _ := strconv.Atoi(match[3])
+	if weeks > maxGenerateWeeks {
+		message.Respond("I can generate up to %d weeks at a time, try a shorter rota", maxGenerateWeeks)
+		return nil
+	}
+
+	members, err := b.conf.RotaMembers(team)
+	if err != nil {
+		return err
+	}
+
+	existing, err := b.Rota.Shifts(team.Name, period.From, period.From.AddDate(0, 0, 7*weeks))
+	if err != nil {
+		return fmt.Errorf("failed to list shifts of %s %v", team.Name, err)
+	}
+
+	shifts, err := rota.Generate(team.Name, b.Tiers, members, period.From, weeks,
+		rota.GenerateOptions{Weekends: b.conf.RotaCheck().Weekends, Existing: existing})
+	if err != nil {
+		message.Respond("I couldn't generate a rota: %v", err)
+		return nil
+	}
+	if len(shifts) == 0 {
+		message.Respond("The %s rota already has shifts for all of those %d weeks", team.Name, weeks)
+		return nil
+	}
+
+	generation := rotaGeneration{
+		ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
+		Team:      team.Name,
+		Requester: message.AuthorID,
+		Shifts:    shifts,
+	}
+	if err := b.Store.Set(rotaGenerationKey(generation.ID), generation); err != nil {
+		return fmt.Errorf("failed to store generated rota %v", err)
+	}
+
+	approve := slackAPI.NewButtonBlockElement(actionRotaGenerateApprove, generation.ID,
+		slackAPI.NewTextBlockObject(slackAPI.PlainTextType, "Write to calendar", false, false))
+	approve.Style = slackAPI.StylePrimary
+	discard := slackAPI.NewButtonBlockElement(actionRotaGenerateDiscard, generation.ID,
+		slackAPI.NewTextBlockObject(slackAPI.PlainTextType, "Discard", false, false))
+
+	title := fmt.Sprintf("Here's a %d week rota for %s starting %s:", weeks, team.Name, period.From.Format("Mon 2 Jan"))
+	if len(existing) > 0 {
+		title = fmt.Sprintf("Here's a %d week rota for %s starting %s, keeping the %d shifts already on it:",
+			weeks, team.Name, period.From.Format("Mon 2 Jan"), len(existing))
+	}
+	blocks := []slackAPI.Block{markdownSection(title)}
+	for _, week := range b.formatShiftsByWeek(shifts, period.From) {
+		blocks = append(blocks, markdownSection(week))
+	}
+	blocks = append(blocks,
+		markdownSection("*Shifts per person*\n"+formatShiftCounts(shifts)),
+		slackAPI.NewActionBlock("rota_generate", approve, discard),
+	)
+
+	_, _, err = b.Slack.PostMessage(message.Channel, slackAPI.MsgOptionText(title, false), slackAPI.MsgOptionBlocks(blocks...))
+	if err != nil {
+		return fmt.Errorf("failed to post generated rota %v", err)
+	}
+
+	return nil
+}
+
+// RotaGenerationResponse writes or drops a generated rota once someone clicked a button
+func (b *Bot) RotaGenerationResponse(callback slackAPI.InteractionCallback, action *slackAPI.BlockAction) error {
+	var generation rotaGeneration
+	ok, err := b.Store.Get(rotaGenerationKey(action.Value), &generation)
+	if err != nil {
+		return fmt.Errorf("failed to load generated rota %v", err)
+	}
+	if !ok {
+		b.replaceInteractiveMessage(callback, "This rota has already been handled")
+		return nil
+	}
+
+	if action.ActionID == actionRotaGenerateDiscard {
+		if _, err := b.Store.Delete(rotaGenerationKey(generation.ID)); err != nil {
+			return fmt.Errorf("failed to delete generated rota %v", err)
+		}
+		b.replaceInteractiveMessage(callback, fmt.Sprintf("<@%s> discarded the generated %s rota", callback.User.ID, generation.Team))
+		return nil
+	}
+
+	writer, ok := b.Rota.(rota.Writer)
+	if !ok {
+		return fmt.Errorf("rota provider does not support changes")
+	}
+
+	// Another rota may have been approved since the preview, its shifts are kept
+	var existing []rota.Shift
+	if n := len(generation.Shifts); generation.Written < n {
+		existing, err = b.Rota.Shifts(generation.Team, generation.Shifts[generation.Written].Start, generation.Shifts[n-1].End)
+		if err != nil {
+			return fmt.Errorf("failed to list shifts of %s %v", generation.Team, err)
+		}
+	}
+
+	skipped := 0
+	for ; generation.Written < len(generation.Shifts); generation.Written++ {
+		s := generation.Shifts[generation.Written]
+		if overlapsTier(existing, s) {
+			skipped++
+			continue
+		}
+		if err := writer.Create(s); err != nil {
+			// Keeping the rota and its buttons, approving again writes the rest
+			if err := b.Store.Set(rotaGenerationKey(generation.ID), generation); err != nil {
+				b.Logger.Error(fmt.Sprintf("failed to store generated rota %v", err))
+			}
+			b.notify(callback.User.ID, fmt.Sprintf("Sorry, I failed after writing %d of %d shifts, click Write to calendar again to write the rest",
+				generation.Written, len(generation.Shifts)))
+			return fmt.Errorf("failed to write generated shift %v", err)
+		}
+	}
+	if _, err := b.Store.Delete(rotaGenerationKey(generation.ID)); err != nil {
+		return fmt.Errorf("failed to delete generated rota %v", err)
+	}
+
+	text := fmt.Sprintf("<@%s> approved the generated %s rota, I've written %d shifts to the calendar",
+		callback.User.ID, generation.Team, len(generation.Shifts)-skipped)
+	if skipped > 0 {
+		text += fmt.Sprintf(" and skipped %d that clashed with shifts added since", skipped)
+	}
+	b.replaceInteractiveMessage(callback, text)
+	return nil
+}
+
+// overlapsTier reports whether a shift of the same tier overlaps the shift
+func overlapsTier(shifts []rota.Shift, shift rota.Shift) bool {
+	for _, s := range shifts {
+		if s.Tier == shift.Tier && s.Overlaps(shift.Start, shift.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatShiftsByWeek renders the shifts as one block of lines per week, the
+// weeks are counted from the start of the rota like Generate counts them
+func (b *Bot) formatShiftsByWeek(shifts []rota.Shift, from time.Time) []string {
+	names := map[string]string{}
+	var weeks []string
+	var lines []string
+	week := from
+	for _, s := range shifts {
+		if lines == nil || !s.Start.Before(week.AddDate(0, 0, 7)) {
+			for !s.Start.Before(week.AddDate(0, 0, 7)) {
+				week = week.AddDate(0, 0, 7)
+			}
+			if len(lines) > 0 {
+				weeks = append(weeks, strings.Join(lines, "\n"))
+			}
+			lines = []string{fmt.Sprintf("*Week of %s*", week.Format("Mon 2 Jan"))}
+		}
+		if _, ok := names[s.Email]; !ok {
+			names[s.Email] = b.slackNameForEmail(s.Email)
+		}
+		lines = append(lines, fmt.Sprintf("• %s %s %s", s.Tier, formatShiftSpan(s), names[s.Email]))
+	}
+	if len(lines) > 0 {
+		weeks = append(weeks, strings.Join(lines, "\n"))
+	}
+	return weeks
+}
+
+// formatShiftCounts renders how many shifts of each tier everyone got
+func formatShiftCounts(shifts []rota.Shift) string {
+	counts := map[string]map[string]int{}
+	for _, s := range shifts {
+		if counts[s.Email] == nil {
+			counts[s.Email] = map[string]int{}
+		}
+		counts[s.Email][s.Tier]++
+	}
+
+	emails := make([]string, 0, len(counts))
+	for email := range counts {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+
+	lines := make([]string, 0, len(emails))
+	for _, email := range emails {
+		tiers := make([]string, 0, len(counts[email]))
+		for tier, n := range counts[email] {
+			tiers = append(tiers, fmt.Sprintf("%d %s", n, tier))
+		}
+		sort.Strings(tiers)
+		lines = append(lines, fmt.Sprintf("• %s: %s", email, strings.Join(tiers, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dombo/hiberBot/pkg/bot/rota"
+	"github.com/go-joe/joe"
+	slackAPI "github.com/slack-go/slack"
+)
+
+// GetRotaStats answers "rota stats [team] [period]", the period defaults to this month
+func (b *Bot) GetRotaStats(message joe.Message, args string) error {
+	team := b.conf.TeamForChannel(message.Channel)
+	if fields := strings.Fields(args); len(fields) > 0 {
+		if t, ok := b.conf.Team(fields[0]); ok {
+			team = t
+			args = strings.Join(fields[1:], " ")
+		}
+	}
+	if args == "" {
+		args = "this month"
+	}
+
+	period, err := rota.ParsePeriod(args, b.teamNow(team.Name))
+	if err != nil {
+		message.Respond("%v. Try @%s rota stats [team] [last month|this week|2020-06-01 to 2020-06-30]", err, b.Bot.Name)
+		return nil
+	}
+
+	return b.postRotaStats(message.Channel, team.Name, period)
+}
+
+// MonthlyReportRotaStats posts last month's on-call load to the team's questions channel
+func (b *Bot) MonthlyReportRotaStats(team string) {
+	teamConf, ok := b.conf.Team(team)
+	if !ok || teamConf.QuestionsChannel == "" {
+		b.Logger.Info(fmt.Sprintf("no questions channel configured for team %s", team))
+		return
+	}
+
+	period, _ := rota.ParsePeriod("last month", time.Now().In(teamConf.Location()))
+	if err := b.postRotaStats(teamConf.QuestionsChannel, team, period); err != nil {
+		b.Logger.Error(fmt.Sprintf("rota stats error %v", err))
+	}
+}
+
+// postRotaStats posts the on-call load of the team as a table and attaches it as CSV
+func (b *Bot) postRotaStats(channelID, team string, period rota.Period) error {
+	to := period.To.Add(time.Nanosecond) // the end of a period is inclusive
+	holidays, err := b.holidays(team, period.From, to)
+	if err != nil {
+		return fmt.Errorf("failed to look up the holidays of %s %v", team, err)
+	}
+
+	loads, err := rota.Stats(b.Rota, team, b.Tiers, period.From, to, rota.StatsOptions{Holidays: holidays})
+	if err != nil {
+		return fmt.Errorf("failed to work out the %s rota stats %v", team, err)
+	}
+
+	span := fmt.Sprintf("%s to %s", period.From.Format("Mon 2 Jan 2006"), period.To.Format("Mon 2 Jan 2006"))
+	if len(loads) == 0 {
+		b.notify(channelID, fmt.Sprintf("Nobody was on the %s rota from %s", team, span))
+		return nil
+	}
+
+	rows := b.rotaStatsRows(loads)
+
+	var table bytes.Buffer
+	w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	var csvFile bytes.Buffer
+	if err := csv.NewWriter(&csvFile).WriteAll(rows); err != nil {
+		return err
+	}
+
+	b.notify(channelID, fmt.Sprintf("On-call load of the %s rota from %s:\n```\n%s```", team, span, table.String()))
+
+	_, err = b.Slack.UploadFile(slackAPI.FileUploadParameters{
+		Content:  csvFile.String(),
+		Filetype: "csv",
+		Filename: fmt.Sprintf("%s-rota-%s-%s.csv", team, period.From.Format("2006-01-02"), period.To.Format("2006-01-02")),
+		Title:    fmt.Sprintf("%s rota stats", team),
+		Channels: []string{channelID},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload rota stats %v", err)
+	}
+
+	return nil
+}
+
+// rotaStatsRows lays the loads out as a header row and one row per person
+func (b *Bot) rotaStatsRows(loads []rota.Load) [][]string {
+	header := []string{"Person"}
+	for _, tier := range b.Tiers {
+		header = append(header, fmt.Sprintf("%s %s", tier.Name, tier.Window.Unit()))
+	}
+	header = append(header, "Weekend days", "Holidays", "Covered days")
+
+	rows := [][]string{header}
+	for _, l := range loads {
+		row := []string{l.Email}
+		for _, tier := range b.Tiers {
+			row = append(row, strconv.Itoa(l.Windows[tier.Name]))
+		}
+		row = append(row, strconv.Itoa(l.WeekendDays), strconv.Itoa(l.HolidayDays), strconv.Itoa(l.CoveredDays))
+		rows = append(rows, row)
+	}
+	return rows
+}
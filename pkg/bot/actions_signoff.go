@@ -0,0 +1,346 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dombo/hiberBot/pkg/bot/rota"
+	"github.com/go-joe/joe"
+	slackAPI "github.com/slack-go/slack"
+)
+
+// Block Kit action ID of the buttons ticking off sign-off checklist items
+const actionSignoffItem = "signoff_item"
+
+// signoff is the end of shift checklist of the first tier of a team on a day.
+// They are kept after the day is over so they can be audited.
+type signoff struct {
+	Team      string
+	Date      string // day of the shift in the team's time zone, 2006-01-02
+	Email     string // rota email of who had to sign off
+	UserID    string
+	Items     []signoffItem
+	Channel   string // where the checklist message was posted
+	Timestamp string
+	Completed time.Time // zero until every item is ticked off
+	Escalated string    // Slack user ID of the second tier told about a missing sign-off
+}
+
+type signoffItem struct {
+	Text string
+	Done time.Time // zero while not done
+}
+
+func signoffKey(team, date string) string {
+	return fmt.Sprintf("signoff.%s.%s", team, date)
+}
+
+func (s signoff) key() string {
+	return signoffKey(s.Team, s.Date)
+}
+
+// who mentions whoever had to sign off, by email when they have no Slack user
+func (s signoff) who() string {
+	switch {
+	case s.UserID != "":
+		return fmt.Sprintf("<@%s>", s.UserID)
+	case s.Email != "":
+		return s.Email
+	}
+	return "nobody"
+}
+
+// left returns the items that aren't done yet
+func (s signoff) left() []string {
+	var left []string
+	for _, item := range s.Items {
+		if item.Done.IsZero() {
+			left = append(left, item.Text)
+		}
+	}
+	return left
+}
+
+// teamDate returns today in the team's time zone as used in the sign-off keys
+func (b *Bot) teamDate(team string) string {
+	return b.teamNow(team).Format("2006-01-02")
+}
+
+func (b *Bot) loadSignoff(key string) (signoff, bool, error) {
+	var s signoff
+	ok, err := b.Store.Get(key, &s)
+	if err != nil {
+		return s, false, fmt.Errorf("failed to load sign-off %s %v", key, err)
+	}
+	return s, ok, nil
+}
+
+func (b *Bot) saveSignoff(s signoff) error {
+	if err := b.Store.Set(s.key(), s); err != nil {
+		return fmt.Errorf("failed to save sign-off %s %v", s.key(), err)
+	}
+	return nil
+}
+
+// DailySendLevel1TheSignoffReminder sends the first tier the sign-off message
+// followed by the checklist they tick off before their shift ends
+func (b *Bot) DailySendLevel1TheSignoffReminder(team string) {
+	shift, err := rota.OnCall(b.Rota, team, b.Tiers[0], b.teamNow(team))
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("level 1 rota user retrieval error %v", err))
+		return
+	}
+	level1, err := b.Identities.User(shift.Email)
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("level 1 rota user retrieval error %v", err))
+		return
+	}
+
+	b.sendMessage(MessageSignoff, team, level1.ID)
+
+	teamConf, _ := b.conf.Team(team)
+	s := signoff{Team: team, Date: b.teamDate(team), Email: shift.Email, UserID: level1.ID}
+	for _, text := range teamConf.SignoffItems() {
+		s.Items = append(s.Items, signoffItem{Text: text})
+	}
+
+	channelID, timestamp, err := b.Slack.PostMessage(level1.ID,
+		slackAPI.MsgOptionText("Sign-off checklist", false),
+		slackAPI.MsgOptionBlocks(signoffBlocks(s)...),
+	)
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("error sending the %s sign-off checklist %v", team, err))
+		return
+	}
+	s.Channel, s.Timestamp = channelID, timestamp
+	if err := b.saveSignoff(s); err != nil {
+		b.Logger.Error(err.Error())
+	}
+}
+
+// SignoffItemResponse ticks off or unticks a checklist item once the first tier clicked its button
+func (b *Bot) SignoffItemResponse(callback slackAPI.InteractionCallback, action *slackAPI.BlockAction) error {
+	sep := strings.LastIndex(action.Value, "#")
+	if sep < 0 {
+		return fmt.Errorf("invalid sign-off item %q", action.Value)
+	}
+	i, err := strconv.Atoi(action.Value[sep+1:])
+	if err != nil {
+		return fmt.Errorf("invalid sign-off item %q", action.Value)
+	}
+
+	s, ok, err := b.loadSignoff(action.Value[:sep])
+	if err != nil {
+		return err
+	}
+	if !ok || i < 0 || i >= len(s.Items) {
+		b.replaceInteractiveMessage(callback, "This sign-off checklist is no longer around")
+		return nil
+	}
+	if callback.User.ID != s.UserID {
+		return nil
+	}
+
+	if s.Items[i].Done.IsZero() {
+		s.Items[i].Done = time.Now()
+	} else {
+		s.Items[i].Done = time.Time{}
+	}
+
+	wasCompleted := !s.Completed.IsZero()
+	if len(s.left()) == 0 {
+		if !wasCompleted {
+			s.Completed = time.Now()
+		}
+	} else {
+		s.Completed = time.Time{}
+	}
+	if err := b.saveSignoff(s); err != nil {
+		return err
+	}
+
+	_, _, _, err = b.Slack.UpdateMessage(callback.Channel.ID, callback.Message.Timestamp,
+		slackAPI.MsgOptionText("Sign-off checklist", false),
+		slackAPI.MsgOptionBlocks(signoffBlocks(s)...),
+	)
+	if err != nil {
+		return fmt.Errorf("error updating the sign-off checklist %v", err)
+	}
+
+	if !wasCompleted && !s.Completed.IsZero() && s.Escalated != "" {
+		b.notify(s.Escalated, fmt.Sprintf("<@%s> has signed off %s after all", s.UserID, s.Team))
+	}
+	return nil
+}
+
+// DailyEscalateMissingSignoff tells the second tier and the team's questions
+// channel when the first tier hasn't finished today's sign-off checklist
+func (b *Bot) DailyEscalateMissingSignoff(team string) {
+	s, ok, err := b.loadSignoff(signoffKey(team, b.teamDate(team)))
+	if err != nil {
+		b.Logger.Error(err.Error())
+		return
+	}
+	if !ok {
+		// The checklist never went out, e.g. Slack or the identity lookup
+		// failed, so nothing on it was signed off either
+		s = b.missingSignoff(team)
+	}
+	if !s.Completed.IsZero() || s.Escalated != "" {
+		return
+	}
+
+	left := s.left()
+	text := fmt.Sprintf("%s hasn't finished the %s sign-off of %s, still to do: %s",
+		s.who(), b.Tiers[0].Name, team, strings.Join(left, ", "))
+
+	escalatedTo := "nobody is on a second tier to escalate to"
+	if len(b.Tiers) > 1 {
+		level2, err := b.getOnCallUser(team, b.Tiers[1], b.teamNow(team))
+		if err != nil && err != rota.ErrNoShift {
+			b.Logger.Error(fmt.Sprintf("%s rota user retrieval error %v", b.Tiers[1].Name, err))
+		}
+		if level2 != nil {
+			b.notify(level2.ID, text+". Could you check in with them?")
+			s.Escalated = level2.ID
+			escalatedTo = fmt.Sprintf("escalated to <@%s>", level2.ID)
+		}
+	}
+	if s.Escalated == "" {
+		// Not escalating again on the next end of day event
+		s.Escalated = "nobody"
+	}
+	if err := b.saveSignoff(s); err != nil {
+		b.Logger.Error(err.Error())
+	}
+
+	if teamConf, ok := b.conf.Team(team); ok && teamConf.QuestionsChannel != "" {
+		b.notify(teamConf.QuestionsChannel, fmt.Sprintf(":warning: %s, %s", text, escalatedTo))
+	}
+	b.recordForHandover(team, "Sign-off", fmt.Sprintf("%d of %d items left undone, %s", len(left), len(s.Items), escalatedTo))
+}
+
+// missingSignoff returns today's sign-off of the team with nothing done, for
+// when the checklist was never sent
+func (b *Bot) missingSignoff(team string) signoff {
+	teamConf, _ := b.conf.Team(team)
+	s := signoff{Team: team, Date: b.teamDate(team)}
+	for _, text := range teamConf.SignoffItems() {
+		s.Items = append(s.Items, signoffItem{Text: text})
+	}
+
+	shift, err := rota.OnCall(b.Rota, team, b.Tiers[0], b.teamNow(team))
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("level 1 rota user retrieval error %v", err))
+		return s
+	}
+	s.Email = shift.Email
+	if level1, err := b.Identities.User(shift.Email); err == nil && level1 != nil {
+		s.UserID = level1.ID
+	}
+	return s
+}
+
+// GetSignoffs answers "signoff [team] [period]" with the sign-offs of the
+// team for audits, the period defaults to this month
+func (b *Bot) GetSignoffs(message joe.Message) error {
+	args := strings.TrimSpace(message.Text[len("signoff"):])
+	team := b.conf.TeamForChannel(message.Channel)
+	if fields := strings.Fields(args); len(fields) > 0 {
+		if t, ok := b.conf.Team(fields[0]); ok {
+			team = t
+			args = strings.Join(fields[1:], " ")
+		}
+	}
+	if args == "" {
+		args = "this month"
+	}
+
+	period, err := rota.ParsePeriod(args, b.teamNow(team.Name))
+	if err != nil {
+		message.Respond("%v. Try @%s signoff [team] [last month|this week|2020-06-01 to 2020-06-30]", err, b.Bot.Name)
+		return nil
+	}
+
+	keys, err := b.Store.Keys()
+	if err != nil {
+		return fmt.Errorf("failed to list sign-offs %v", err)
+	}
+
+	prefix := signoffKey(team.Name, "")
+	var signoffs []signoff
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		day, err := time.ParseInLocation("2006-01-02", strings.TrimPrefix(key, prefix), team.Location())
+		if err != nil || day.Before(period.From) || day.After(period.To) {
+			continue
+		}
+
+		s, ok, err := b.loadSignoff(key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			signoffs = append(signoffs, s)
+		}
+	}
+
+	if len(signoffs) == 0 {
+		message.Respond("There are no %s sign-offs from %s to %s", team.Name,
+			period.From.Format("Mon 2 Jan"), period.To.Format("Mon 2 Jan"))
+		return nil
+	}
+
+	sort.Slice(signoffs, func(i, j int) bool { return signoffs[i].Date < signoffs[j].Date })
+	lines := []string{fmt.Sprintf("Sign-offs of %s from %s to %s:", team.Name,
+		period.From.Format("Mon 2 Jan"), period.To.Format("Mon 2 Jan"))}
+	for _, s := range signoffs {
+		lines = append(lines, formatSignoff(s))
+	}
+	message.Respond("%s", strings.Join(lines, "\n"))
+	return nil
+}
+
+// formatSignoff renders one audit line of a sign-off
+func formatSignoff(s signoff) string {
+	day := s.Date
+	if d, err := time.Parse("2006-01-02", s.Date); err == nil {
+		day = d.Format("Mon 2 Jan")
+	}
+	done := len(s.Items) - len(s.left())
+
+	status := fmt.Sprintf("incomplete (%d/%d)", done, len(s.Items))
+	if !s.Completed.IsZero() {
+		status = fmt.Sprintf("signed off at %s", s.Completed.Format("15:04"))
+	}
+	if s.Escalated != "" && s.Escalated != "nobody" {
+		status += fmt.Sprintf(", escalated to <@%s>", s.Escalated)
+	}
+	return fmt.Sprintf("• %s %s %s", day, s.who(), status)
+}
+
+// signoffBlocks renders the checklist with a button per item
+func signoffBlocks(s signoff) []slackAPI.Block {
+	blocks := []slackAPI.Block{markdownSection(fmt.Sprintf("*Sign-off checklist* for %s", s.Team))}
+	for i, item := range s.Items {
+		text, label := ":white_medium_square: "+item.Text, "Done"
+		if !item.Done.IsZero() {
+			text, label = fmt.Sprintf(":white_check_mark: ~%s~", item.Text), "Undo"
+		}
+
+		button := slackAPI.NewButtonBlockElement(actionSignoffItem, fmt.Sprintf("%s#%d", s.key(), i),
+			slackAPI.NewTextBlockObject(slackAPI.PlainTextType, label, false, false))
+		blocks = append(blocks, slackAPI.NewSectionBlock(
+			slackAPI.NewTextBlockObject(slackAPI.MarkdownType, text, false, false), nil, slackAPI.NewAccessory(button)))
+	}
+
+	if !s.Completed.IsZero() {
+		blocks = append(blocks, markdownSection(fmt.Sprintf("Signed off at %s, thanks!", s.Completed.Format("15:04"))))
+	}
+	return blocks
+}
@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dombo/hiberBot/pkg/bot/rota"
+	slackAPI "github.com/slack-go/slack"
+)
+
+// maxUsergroupSyncAttempts is how often a failed sync is tried before waiting for the next boundary
+const maxUsergroupSyncAttempts = 5
+
+// SyncUsergroups sets the members of the team's usergroups to whoever is on
+// call now and schedules the next sync for the next shift boundary. When
+// Slack fails the sync is retried with a growing delay.
+func (b *Bot) SyncUsergroups(team string, attempt int) {
+	teamConf, ok := b.conf.Team(team)
+	if !ok || len(teamConf.Usergroups) == 0 {
+		return
+	}
+
+	at := time.Now().In(teamConf.Location())
+	err := b.syncUsergroups(teamConf, at)
+	if err == nil {
+		b.scheduleUsergroupSync(team, at)
+		return
+	}
+
+	if attempt+1 >= maxUsergroupSyncAttempts {
+		b.Logger.Error(fmt.Sprintf("giving up syncing the %s usergroups after %d attempts %v", team, attempt+1, err))
+		b.scheduleUsergroupSync(team, at)
+		return
+	}
+
+	delay := time.Duration(1<<attempt) * 10 * time.Second
+	var rateLimited *slackAPI.RateLimitedError
+	if errors.As(err, &rateLimited) && rateLimited.RetryAfter > delay {
+		delay = rateLimited.RetryAfter
+	}
+	b.Logger.Error(fmt.Sprintf("failed to sync the %s usergroups, retrying in %s %v", team, delay, err))
+	b.resetUsergroupTimer(team, delay, UsergroupSyncEvent{Team: team, Attempt: attempt + 1})
+}
+
+// syncUsergroups updates every usergroup of the team, returning the first error
+func (b *Bot) syncUsergroups(team TeamConfig, at time.Time) error {
+	rotas, err := b.getRota(team.Name, at)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, g := range team.Usergroups {
+		var members []string
+		for _, r := range rotas {
+			if r.User == nil || (g.Tier != "" && !strings.EqualFold(g.Tier, r.Tier.Name)) {
+				continue
+			}
+			if !contains(members, r.User.ID) {
+				members = append(members, r.User.ID)
+			}
+		}
+
+		if len(members) == 0 {
+			// Slack doesn't allow empty usergroups, keep the last people on call
+			b.Logger.Info(fmt.Sprintf("nobody is on call for usergroup %s of %s, leaving it as it is", g.Id, team.Name))
+			continue
+		}
+
+		if err := b.syncUsergroup(g.Id, members); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// syncUsergroup sets the members of a usergroup when they differ, logging the change
+func (b *Bot) syncUsergroup(groupID string, members []string) error {
+	before, err := b.Slack.GetUserGroupMembers(groupID)
+	if err != nil {
+		return fmt.Errorf("failed to get members of usergroup %s: %w", groupID, err)
+	}
+
+	sort.Strings(before)
+	sort.Strings(members)
+	if strings.Join(before, ",") == strings.Join(members, ",") {
+		b.Logger.Info(fmt.Sprintf("usergroup %s is up to date: %s", groupID, strings.Join(members, ", ")))
+		return nil
+	}
+
+	if _, err := b.Slack.UpdateUserGroupMembers(groupID, strings.Join(members, ",")); err != nil {
+		return fmt.Errorf("failed to update members of usergroup %s: %w", groupID, err)
+	}
+	b.Logger.Info(fmt.Sprintf("usergroup %s members changed from %s to %s",
+		groupID, strings.Join(before, ", "), strings.Join(members, ", ")))
+	return nil
+}
+
+// scheduleUsergroupSync sets the team's next sync to the next time the rota changes
+func (b *Bot) scheduleUsergroupSync(team string, after time.Time) {
+	next, err := rota.NextChange(b.Rota, team, b.Tiers, after)
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("failed to find the next %s shift boundary, relying on the hourly sync %v", team, err))
+		return
+	}
+	b.resetUsergroupTimer(team, time.Until(next), UsergroupSyncEvent{Team: team})
+}
+
+// resetUsergroupTimer replaces the team's pending sync with one emitting evt after the delay
+func (b *Bot) resetUsergroupTimer(team string, delay time.Duration, evt UsergroupSyncEvent) {
+	if timer, ok := b.usergroupTimers[team]; ok {
+		timer.Stop()
+	}
+	b.usergroupTimers[team] = time.AfterFunc(delay, func() {
+		b.Brain.Emit(evt)
+	})
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
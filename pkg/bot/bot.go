@@ -3,7 +3,11 @@ package bot
 import (
 	"fmt"
 	httpserver "github.com/dombo/hiberBot/pkg/bot/custom-http-server"
+	"time"
 
+	"github.com/dombo/hiberBot/pkg/bot/identity"
+	"github.com/dombo/hiberBot/pkg/bot/postmortem"
+	"github.com/dombo/hiberBot/pkg/bot/rota"
 	"github.com/dombo/hiberBot/pkg/bot/services/google"
 	"github.com/go-joe/cron"
 	"github.com/go-joe/joe"
@@ -15,65 +19,190 @@ import (
 )
 
 type Bot struct {
-	*joe.Bot        // Anonymously embed the joe.Bot type so we can use its functions easily.
-	conf     Config // You can keep other fields here as well.
-	Slack    *slackAPI.Client
-	Calendar *calendar.Service
-	Docs     *docs.Service
-	Drive    *drive.Service
-	Actions	 string
+	*joe.Bot           // Anonymously embed the joe.Bot type so we can use its functions easily.
+	conf        Config // You can keep other fields here as well.
+	Slack       *slackAPI.Client
+	Calendar    *calendar.Service
+	Docs        *docs.Service
+	Drive       *drive.Service
+	Postmortems postmortem.Store // nil when the google store has no credentials
+	Rota        rota.Provider
+	Absences    rota.AbsenceSource            // nil without Google Calendar credentials
+	Holidays    map[string]rota.HolidaySource // by lower case region
+	Identities  *identity.Directory
+	Tiers       []rota.Tier
+	Actions     string
+
+	usergroupTimers map[string]*time.Timer // next usergroup sync per team
+}
+
+// The daily lifecycle events are scheduled for each team separately
+type StartOfDayEvent struct{ Team string }
+type BeforeEndOfDayEvent struct{ Team string }
+type EndOfDayEvent struct{ Team string }
+type RotaCheckEvent struct{ Team string }
+type RotaStatsEvent struct{ Team string }
+type RotaAbsenceEvent struct{ Team string }
+
+// UsergroupSyncEvent is emitted at shift boundaries and hourly for teams with
+// usergroups, Attempt counts retries after Slack errors
+type UsergroupSyncEvent struct {
+	Team    string
+	Attempt int
 }
 
-type StartOfDayEvent struct{}
-type BeforeEndOfDayEvent struct{}
-type EndOfDayEvent struct{}
+// JobEvent runs the configured job of the name
+type JobEvent struct{ Name string }
+
+// IncidentArchiveEvent is emitted once the channel of a resolved incident should be archived
+type IncidentArchiveEvent struct{ ID string }
+
+// IncidentUpdateEvent is emitted once a status update of an open incident may be due
+type IncidentUpdateEvent struct{ ID string }
+
+// RotaReloadEvent is scheduled when the rota provider reads its shifts up front
+type RotaReloadEvent struct{}
 
 func NewBot(conf *Config) (*Bot, error) {
 	if err := conf.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration %w", err)
 	}
 
-	modules := append(conf.Modules(), // TODO Shift these for local time
-		cron.ScheduleEvent("30 6 * * 1-5", StartOfDayEvent{}),
-		cron.ScheduleEvent("30 14 * * 1-5", BeforeEndOfDayEvent{}),
-		cron.ScheduleEvent("30 15 * * 1-5", EndOfDayEvent{}),
-	)
+	var calendarService *calendar.Service
+	if conf.UsesGoogleCalendar() {
+		calendarService = google.NewCalendarService(
+			conf.Google.Calendar.User,
+			[]string{
+				calendar.CalendarEventsScope, // Needed to write rota overrides
+			})
+	}
+
+	rotaProvider, err := conf.RotaProvider(calendarService)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rota provider %w", err)
+	}
+
+	holidays, err := conf.HolidaySources(calendarService)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create holiday sources %w", err)
+	}
+
+	slackClient := slackAPI.New(conf.Slack.Token, slackAPI.OptionDebug(conf.Slack.Debug))
+	identities, err := conf.IdentityDirectory(slackClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create identity directory %w", err)
+	}
+
+	// The feeds list everyone's shifts and emails, they are only served with a token
+	var httpOpts []httpserver.Option
+	if conf.Rota.Feed.Token != "" {
+		httpOpts = append(httpOpts, httpserver.WithHandler("/rota/", &rotaFeed{conf: *conf, rota: rotaProvider, identities: identities}))
+	}
+	modules := conf.Modules(httpOpts...)
+	if _, ok := rotaProvider.(rota.Loader); ok {
+		modules = append(modules, cron.ScheduleEventEvery(conf.RotaReload(), RotaReloadEvent{}))
+	}
+	for _, team := range conf.RotaTeams() {
+		modules = append(modules,
+			cron.ScheduleEvent(team.Cron(team.Schedule.StartOfDay), StartOfDayEvent{Team: team.Name}),
+			cron.ScheduleEvent(team.Cron(team.Schedule.BeforeEndOfDay), BeforeEndOfDayEvent{Team: team.Name}),
+			cron.ScheduleEvent(team.Cron(team.Schedule.EndOfDay), EndOfDayEvent{Team: team.Name}),
+			cron.ScheduleEvent(team.Cron(conf.RotaCheck().Schedule), RotaCheckEvent{Team: team.Name}),
+			cron.ScheduleEvent(team.Cron(conf.RotaStats().Schedule), RotaStatsEvent{Team: team.Name}),
+		)
+		if calendarService != nil {
+			modules = append(modules, cron.ScheduleEvent(team.Cron(conf.RotaAbsence().Schedule), RotaAbsenceEvent{Team: team.Name}))
+		}
+		if len(team.Usergroups) > 0 {
+			// Catches rota changes between boundaries, see SyncUsergroups
+			modules = append(modules, cron.ScheduleEventEvery(time.Hour, UsergroupSyncEvent{Team: team.Name}))
+		}
+	}
+
+	for _, job := range conf.Jobs {
+		modules = append(modules, cron.ScheduleEvent(conf.JobTeam(job).Cron(job.Schedule), JobEvent{Name: job.Name}))
+	}
 
 	b := &Bot{
 		Bot: joe.New(conf.Slack.BotName,
 			modules...),
-		Slack: slackAPI.New(conf.Slack.Token, slackAPI.OptionDebug(conf.Slack.Debug)),
-		Calendar: google.NewCalendarService(
-			conf.Google.Calendar.User,
-			[]string{
-				calendar.CalendarReadonlyScope,
-			}),
-		Docs: google.NewDocsService(
+		conf:       *conf,
+		Calendar:   calendarService,
+		Rota:       rotaProvider,
+		Holidays:   holidays,
+		Tiers:      conf.RotaTiers(),
+		Slack:      slackClient,
+		Identities: identities,
+
+		usergroupTimers: map[string]*time.Timer{},
+	}
+
+	if calendarService != nil {
+		var calendars []string
+		for _, team := range conf.RotaTeams() {
+			calendars = append(calendars, team.RotaCalendarId)
+		}
+		b.Absences = rota.NewGoogleAbsences(calendarService, conf.RotaAbsence().Keywords, conf.RotaTiers(), calendars)
+	}
+
+	if conf.UsesGoogleDocs() {
+		b.Docs = google.NewDocsService(
 			conf.Google.Docs.User,
 			[]string{
 				docs.DocumentsScope, // TODO Can we reduce these permissions somehow
-			}),
-		Drive: google.NewDriveService(
+			})
+		b.Drive = google.NewDriveService(
 			conf.Google.Drive.User,
 			[]string{
 				drive.DriveScope, // TODO Can we reduce these permissions somehow
-			}),
+			})
 	}
 
+	postmortems, err := conf.PostmortemStore(b.Docs, b.Drive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create postmortem store %w", err)
+	}
+	b.Postmortems = postmortems
+
 	// Events API authentication handled in custom server.go implementation
 	//b.Brain.RegisterHandler(b.MessageRouter)
 
 	b.Brain.RegisterHandler(b.StartupHook)
 	b.Brain.RegisterHandler(b.ShutdownHook)
 	b.Brain.RegisterHandler(b.CommandsRouter)
+	b.Brain.RegisterHandler(b.AtStartOfDay)
+	b.Brain.RegisterHandler(b.BeforeEndOfDay)
+	b.Brain.RegisterHandler(b.AtEndOfDay)
+	b.Brain.RegisterHandler(b.CheckRota)
+	b.Brain.RegisterHandler(b.ReportRotaStats)
+	b.Brain.RegisterHandler(b.CheckRotaAbsences)
+	b.Brain.RegisterHandler(b.SyncRotaUsergroups)
+	b.Brain.RegisterHandler(b.RunJob)
+	b.Brain.RegisterHandler(b.ArchiveIncident)
+	b.Brain.RegisterHandler(b.RemindIncidentUpdate)
+	b.Brain.RegisterHandler(b.ReloadRota)
 
 	b.Respond("postmortem(.+)?", b.Postmortem)
-	b.Respond("rota", b.GetTodaysRota)
+	b.Respond("rota(.+)?", b.GetRota)
+	b.Respond("whois (.+)", b.Whois)
+	b.Respond("handoff(.+)?", b.Handoff)
+	b.Respond("preview(.+)?", b.Preview)
+	b.Respond("signoff(.+)?", b.GetSignoffs)
+	b.Respond("incident(.+)?", b.Incident)
 
 	return b, nil
 }
 
 func (b *Bot) StartupHook(evt joe.InitEvent) error {
+	for _, team := range b.conf.RotaTeams() {
+		if len(team.Usergroups) > 0 {
+			b.Brain.Emit(UsergroupSyncEvent{Team: team.Name})
+		}
+	}
+	if b.conf.Rota.Feed.Token == "" {
+		b.Logger.Warn("rota feeds are off, set rota.feed.token to serve them")
+	}
+	b.scheduleIncidentTimers()
 	return nil
 }
 
@@ -85,19 +214,67 @@ func (b *Bot) CommandsRouter(evt httpserver.RequestEvent) error {
 	switch evt.URL.Path {
 	case "/test":
 		b.Say("#welcome", "Received test command!")
+	case "/slack/interactions":
+		return b.InteractionsRouter(evt)
 	}
 	return nil
 }
 
-func (b *Bot) AtStartOfDay(StartOfDayEvent) {
-	b.DailySetQuestionsChannelTopic()
-	b.DailySendLevel1TheRunbook()
+func (b *Bot) AtStartOfDay(evt StartOfDayEvent) {
+	b.DailySetQuestionsChannelTopic(evt.Team)
+	if b.skipOnHoliday(evt.Team) {
+		return
+	}
+	b.DailySendLevel1TheRunbook(evt.Team)
+	b.DailySendHandoverDigest(evt.Team)
+	b.DailyRemindAboutPostmortems(evt.Team)
+}
+
+func (b *Bot) BeforeEndOfDay(evt BeforeEndOfDayEvent) {
+	if b.skipOnHoliday(evt.Team) {
+		return
+	}
+	b.DailySendLevel1TheSignoffReminder(evt.Team)
+}
+
+func (b *Bot) AtEndOfDay(evt EndOfDayEvent) {
+	if b.skipOnHoliday(evt.Team) {
+		return
+	}
+	b.DailyEscalateMissingSignoff(evt.Team)
+	b.DailySendLevel1TheCongratulationsMessage(evt.Team)
+}
+
+func (b *Bot) CheckRota(evt RotaCheckEvent) {
+	b.DailyWarnAboutRotaProblems(evt.Team)
+}
+
+func (b *Bot) ReportRotaStats(evt RotaStatsEvent) {
+	b.MonthlyReportRotaStats(evt.Team)
+}
+
+func (b *Bot) CheckRotaAbsences(evt RotaAbsenceEvent) {
+	b.DailyWarnAboutAbsences(evt.Team)
 }
 
-func (b *Bot) BeforeEndOfDay(BeforeEndOfDayEvent) {
-	b.DailySendLevel1TheSignoffReminder()
+func (b *Bot) SyncRotaUsergroups(evt UsergroupSyncEvent) {
+	b.SyncUsergroups(evt.Team, evt.Attempt)
 }
 
-func (b *Bot) AtEndOfDay(EndOfDayEvent) {
-	b.DailySendLevel1TheCongratulationsMessage()
+func (b *Bot) ArchiveIncident(evt IncidentArchiveEvent) {
+	b.ArchiveIncidentChannel(evt.ID)
+}
+
+func (b *Bot) RemindIncidentUpdate(evt IncidentUpdateEvent) {
+	b.RemindAboutIncidentUpdate(evt.ID)
+}
+
+func (b *Bot) ReloadRota(evt RotaReloadEvent) {
+	loader, ok := b.Rota.(rota.Loader)
+	if !ok {
+		return
+	}
+	if err := loader.Load(); err != nil {
+		b.Logger.Error(fmt.Sprintf("failed to reload rota %v", err))
+	}
 }
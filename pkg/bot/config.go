@@ -1,18 +1,126 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
 	httpserver "github.com/dombo/hiberBot/pkg/bot/custom-http-server"
+	"github.com/dombo/hiberBot/pkg/bot/identity"
+	"github.com/dombo/hiberBot/pkg/bot/memory"
+	"github.com/dombo/hiberBot/pkg/bot/postmortem"
+	"github.com/dombo/hiberBot/pkg/bot/rota"
+	"github.com/go-joe/cron"
 	"github.com/go-joe/joe"
 	"github.com/go-joe/slack-adapter/v2"
 	"github.com/spf13/viper"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/drive/v3"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
 )
 
 // Config holds all parameters to setup a new chat bot.
 type Config struct {
-	Slack    SlackConfig
-	Google	GoogleConfig
-	HTTP     HTTPConfig
+	Slack      SlackConfig
+	Google     GoogleConfig
+	HTTP       HTTPConfig
+	Rota       RotaConfig
+	Teams      []TeamConfig
+	Holidays   []HolidayConfig
+	Identity   IdentityConfig
+	Memory     MemoryConfig
+	Jobs       []JobConfig
+	Messages   MessagesConfig
+	Postmortem PostmortemConfig
+	Incident   IncidentConfig
+}
+
+// IncidentConfig controls the channels of declared incidents.
+type IncidentConfig struct {
+	ArchiveAfter string           `mapstructure:"archive_after"` // optional how long after an incident is resolved its channel is archived, defaults to 24h
+	Roles        []string         // optional roles people can take on during incidents besides commander, comms and scribe
+	Severities   []SeverityConfig // optional severities from the worst down, defaults to SEV1 to SEV4, see DefaultSeverities
+}
+
+// SeverityConfig is the policy of incidents declared with a severity.
+type SeverityConfig struct {
+	Name          string   // required e.g. SEV1, incidents are declared with it
+	Description   string   // optional what makes an incident this severe
+	Page          []string // optional rota tiers whose on-call are paged and invited to the incident channel
+	Notify        []string // optional channel IDs told when an incident is declared and resolved
+	UpdateEvery   string   `mapstructure:"update_every"` // optional how often the commander posts a status update, e.g. 30m, none when empty
+	Postmortem    bool     // optional whether a postmortem is mandatory
+	PostmortemDue int      `mapstructure:"postmortem_due"` // optional business days after resolving the postmortem is due, defaults to 5
+}
+
+// UpdateInterval returns how often status updates are due, 0 when they aren't required.
+func (s SeverityConfig) UpdateInterval() time.Duration {
+	d, _ := time.ParseDuration(s.UpdateEvery)
+	return d
+}
+
+// PostmortemDueDays returns the business days a mandatory postmortem is due in.
+func (s SeverityConfig) PostmortemDueDays() int {
+	if s.PostmortemDue == 0 {
+		return 5
+	}
+	return s.PostmortemDue
+}
+
+// Supported values of PostmortemConfig.Store
+const (
+	PostmortemStoreGoogle   = "google"
+	PostmortemStoreMarkdown = "markdown"
+)
+
+// PostmortemConfig picks where postmortems are written.
+type PostmortemConfig struct {
+	Store     string // optional google (default) to copy a Google Docs template, or markdown to write files
+	Templates string // optional directory of the markdown templates, defaults to postmortems/templates
+	Dir       string // optional folder the markdown postmortems are written to, defaults to postmortems
+	URL       string // optional base URL the markdown folder is browsed at, e.g. https://github.com/org/repo/blob/main/postmortems
+}
+
+// MessagesConfig points the daily messages at text/template files rendering
+// Slack Block Kit JSON such as {"text": "...", "blocks": [...]}. Messages
+// without a file use the built-in templates, see defaultMessageTemplates.
+type MessagesConfig struct {
+	Runbook         string // optional template of the runbook sent to the first tier at the start of the day
+	Signoff         string // optional template of the reminder to sign off sent before the end of the day
+	Congratulations string // optional template of the thanks sent to the first tier at the end of the day
+}
+
+// File returns the template file of the named message, empty for the built-in template.
+func (m MessagesConfig) File(name string) string {
+	switch name {
+	case MessageRunbook:
+		return m.Runbook
+	case MessageSignoff:
+		return m.Signoff
+	case MessageCongratulations:
+		return m.Congratulations
+	}
+	return ""
+}
+
+// JobConfig is an extra scheduled job running one of the bot's actions, see
+// jobActions for the list.
+type JobConfig struct {
+	Name     string // required unique name of the job, used in logs
+	Schedule string // required cron expression in the team's time zone
+	Team     string // optional team the job runs for, defaults to the first team
+	Action   string // required what the job does, e.g. topic, runbook or message
+	Channel  string // optional channel ID the message action posts to, defaults to the team's questions channel
+	Text     string // required by the message action, what to post
+}
+
+// MemoryConfig controls where the bot keeps what it remembers, such as
+// handover notes and rota changes waiting for confirmation.
+type MemoryConfig struct {
+	Type string // optional where the state is kept: file (JSON) or bolt (BoltDB), lost on restart when empty
+	Path string // required by file and bolt, path of the file or database
 }
 
 type SlackConfig struct {
@@ -27,6 +135,130 @@ type HTTPConfig struct {
 	ListenAddr string // optional HTTP listen address to receive command callbacks
 }
 
+// Supported values of RotaConfig.Provider
+const (
+	RotaProviderGoogle = "google"
+	RotaProviderFile   = "file"
+	RotaProviderMemory = "memory"
+	RotaProviderICS    = "ics"
+)
+
+type RotaConfig struct {
+	Provider string      // optional where shifts are read from: google (default), file, ics or memory
+	File     string      // required by the file provider, path to a JSON or YAML file of shifts, or the default rota_file of the ics provider
+	Reload   string      // optional how often the file and ics providers re-read their source, defaults to 5m
+	Tiers    []rota.Tier // optional escalation tiers in order, defaults to a daily L1 and a weekly L2
+	Check    RotaCheckConfig
+	Stats    RotaStatsConfig
+	Feed     RotaFeedConfig
+	Absence  RotaAbsenceConfig
+}
+
+// RotaAbsenceConfig controls the daily scan of on-call people's own calendars
+// for time away, which needs Google Calendar credentials.
+type RotaAbsenceConfig struct {
+	Schedule string   // optional cron expression of the scan, defaults to 0 9 * * 1-5
+	Days     int      // optional how many days ahead to scan, defaults to 7
+	Keywords []string // optional words in event summaries marking time away, see rota.DefaultAbsenceKeywords
+}
+
+// RotaStatsConfig controls the scheduled on-call load report.
+type RotaStatsConfig struct {
+	Schedule string // optional cron expression of the report on last month, defaults to 0 9 1 * *
+}
+
+// RotaFeedConfig controls the iCalendar feeds served at /rota/<team>.ics and
+// /rota/<team>/<email>.ics by the HTTP server.
+type RotaFeedConfig struct {
+	Days  int    // optional how many days of shifts to include, defaults to 90
+	Token string // optional secret that must be passed as the token query parameter, the feeds are off without it
+}
+
+// RotaCheckConfig controls the daily scan of the rota for gaps and mistakes.
+type RotaCheckConfig struct {
+	Schedule string // optional cron expression of the check, defaults to 0 9 * * 1-5
+	Days     int    // optional how many days ahead to check, defaults to 14
+	Weekends bool   // optional weekends are on call too, so check and generate them
+}
+
+// TeamConfig describes a team running its own rota. When no teams are
+// configured a single default team uses the global calendar and templates.
+type TeamConfig struct {
+	Name             string            // required unique name used in commands, e.g. @bot rota payments
+	RotaCalendarId   string            `mapstructure:"rota_calendar_id"`  // optional defaults to google.calendar.rota_calendar_id
+	RotaFile         string            `mapstructure:"rota_file"`         // optional path or URL of the .ics rota of the ics provider, defaults to rota.file
+	QuestionsChannel string            `mapstructure:"questions_channel"` // optional channel ID whose topic lists the rota
+	Channels         []string          // optional channel IDs in which commands default to this team
+	Schedule         ScheduleConfig    // optional cron expressions of the daily reminders
+	Timezone         string            // optional IANA time zone of the team's schedules and days, e.g. Europe/London, defaults to the server's
+	PostmortemFileId string            `mapstructure:"postmortem_file_id"` // optional defaults to google.drive.postmortem_file_id
+	PostmortemFile   string            `mapstructure:"postmortem_file"`    // optional markdown template in postmortem.templates, defaults to postmortem.md
+	Members          []MemberConfig    // optional roster used to generate the rota and suggest replacements
+	Lead             string            // optional email of the team lead told about on-call people who are away
+	Region           string            // optional holiday region whose public holidays change the daily reminders
+	OnHoliday        string            `mapstructure:"on_holiday"` // optional what the daily reminders do on public holidays: skip (default) or run
+	Usergroups       []UsergroupConfig // optional Slack usergroups kept in sync with who is on call, needs the usergroups:write scope
+	Runbooks         []LinkConfig      // optional links to the team's runbooks shown in the daily messages
+	Signoff          []string          // optional items of the checklist the first tier ticks off before the end of the day, defaults to DefaultSignoffItems
+}
+
+// LinkConfig is a titled link, e.g. to a runbook.
+type LinkConfig struct {
+	Title string // required text of the link
+	Url   string // required
+}
+
+// UsergroupConfig is a Slack usergroup, e.g. @oncall-l1, whose members are set
+// to the people on call for the team.
+type UsergroupConfig struct {
+	Id   string // required ID of the usergroup, e.g. S0123ABCD
+	Tier string // optional tier whose on-call are the members, every tier when empty
+}
+
+// DefaultSignoffItems is the sign-off checklist of teams that don't configure one
+var DefaultSignoffItems = []string{"Dashboards reviewed", "Alerts triaged", "Handover written"}
+
+// Supported values of TeamConfig.OnHoliday
+const (
+	OnHolidaySkip = "skip"
+	OnHolidayRun  = "run"
+)
+
+// IdentityConfig maps the emails on the rota to Slack users.
+type IdentityConfig struct {
+	CacheTTL string         `mapstructure:"cache_ttl"` // optional how long Slack lookups are cached, defaults to 1h
+	File     string         // optional path to a JSON or YAML file listing more people
+	People   []PersonConfig // optional people whose Slack email differs from the rota or who have several emails
+}
+
+// PersonConfig ties the emails of someone to their Slack user.
+type PersonConfig struct {
+	Email   string   // required email of the person written to the rota
+	SlackId string   `mapstructure:"slack_id"` // optional Slack user ID, instead of looking the emails up in Slack
+	Aliases []string // optional other emails of the person that may appear on the rota
+}
+
+// HolidayConfig is where the public holidays of a region come from, either a
+// Google calendar or a local file.
+type HolidayConfig struct {
+	Region     string // required name teams refer to, e.g. uk
+	CalendarId string `mapstructure:"calendar_id"` // optional Google holiday calendar, e.g. en.uk#holiday@group.v.calendar.google.com
+	File       string // optional path to an .ics file or a JSON or YAML list of holidays
+}
+
+// MemberConfig is a person on a team's roster.
+type MemberConfig struct {
+	Email       string   // required calendar email of the member
+	Unavailable []string // optional dates or date ranges the member can't be on call, e.g. 2020-06-18 to 2020-06-25
+}
+
+// ScheduleConfig holds the cron expressions of a team's daily lifecycle events.
+type ScheduleConfig struct {
+	StartOfDay     string `mapstructure:"start_of_day"`      // optional defaults to 30 6 * * 1-5
+	BeforeEndOfDay string `mapstructure:"before_end_of_day"` // optional defaults to 30 14 * * 1-5
+	EndOfDay       string `mapstructure:"end_of_day"`        // optional defaults to 30 15 * * 1-5
+}
+
 type GoogleConfig struct {
 	Calendar CalendarConfig
 	Docs     DocsConfig
@@ -45,9 +277,9 @@ type DocsConfig struct {
 }
 
 type DriveConfig struct {
-	User string // required drive user to operate as
-	PostmortemFileId string `mapstructure:"postmortem_fild_id"`
-	Service        GoogleCredentialsFile
+	User             string // required drive user to operate as
+	PostmortemFileId string `mapstructure:"postmortem_file_id"`
+	Service          GoogleCredentialsFile
 }
 
 // Publicly exported variant of golang.org/x/oauth2/google/google.go:99 credentialsFile
@@ -98,7 +330,8 @@ func GetConf() *Config {
 }
 
 // Modules creates a list of joe.Modules that can be used with this configuration.
-func (conf Config) Modules() []joe.Module {
+// The options are passed on to the HTTP server.
+func (conf Config) Modules(httpOpts ...httpserver.Option) []joe.Module {
 	var modules []joe.Module
 
 	modules = append(modules, slack.EventsAPIAdapter(viper.GetString("slack.listenaddr"),
@@ -106,14 +339,591 @@ func (conf Config) Modules() []joe.Module {
 		viper.GetString("slack.verification_token"),
 		slack.WithDebug(viper.GetBool("slack.debug"))))
 
-	modules = append(modules, httpserver.Server(viper.GetString("http.listenaddr")))
+	modules = append(modules, httpserver.Server(viper.GetString("http.listenaddr"), httpOpts...))
+
+	if conf.Memory.Type != "" {
+		modules = append(modules, memory.Module(conf.Memory.Type, conf.Memory.Path, memoryMigrations))
+	}
 
 	return modules
 }
 
+// RotaProvider creates the rota.Provider selected by the configuration. The
+// calendar service is only used by the google provider and may be nil otherwise.
+func (conf Config) RotaProvider(srv *calendar.Service) (rota.Provider, error) {
+	switch conf.Rota.Provider {
+	case "", RotaProviderGoogle:
+		calendars := map[string]string{}
+		for _, team := range conf.RotaTeams() {
+			calendars[team.Name] = team.RotaCalendarId
+		}
+		return rota.NewGoogleProvider(srv, calendars, conf.RotaTiers(), conf.RotaLocations()), nil
+	case RotaProviderFile:
+		return rota.NewFileProvider(conf.Rota.File, conf.RotaLocations())
+	case RotaProviderICS:
+		sources := map[string]string{}
+		for _, team := range conf.RotaTeams() {
+			sources[team.Name] = team.RotaFile
+		}
+		return rota.NewICSProvider(sources, conf.RotaTiers(), conf.RotaLocations())
+	case RotaProviderMemory:
+		return rota.NewMemoryProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown rota provider %q", conf.Rota.Provider)
+	}
+}
+
+// RotaLocations returns the time zone of every team.
+func (conf Config) RotaLocations() rota.Locations {
+	locations := rota.Locations{}
+	for _, team := range conf.RotaTeams() {
+		locations[team.Name] = team.Location()
+	}
+	return locations
+}
+
+// HolidaySources creates the holiday source of every region. The calendar
+// service is only used by regions with a calendar ID and may be nil otherwise.
+func (conf Config) HolidaySources(srv *calendar.Service) (map[string]rota.HolidaySource, error) {
+	sources := map[string]rota.HolidaySource{}
+	for _, h := range conf.Holidays {
+		region := strings.ToLower(h.Region)
+		if h.CalendarId != "" {
+			sources[region] = rota.NewGoogleHolidays(srv, h.CalendarId)
+			continue
+		}
+
+		src, err := rota.NewFileHolidays(h.File)
+		if err != nil {
+			return nil, err
+		}
+		sources[region] = src
+	}
+	return sources, nil
+}
+
+// IdentityDirectory creates the directory resolving rota emails to Slack
+// users of the configured and file listed people.
+func (conf Config) IdentityDirectory(slack identity.Slack) (*identity.Directory, error) {
+	people := make([]identity.Person, 0, len(conf.Identity.People))
+	for _, p := range conf.Identity.People {
+		people = append(people, identity.Person{Email: p.Email, SlackID: p.SlackId, Aliases: p.Aliases})
+	}
+
+	if conf.Identity.File != "" {
+		listed, err := identity.ReadPeople(conf.Identity.File)
+		if err != nil {
+			return nil, err
+		}
+		people = append(people, listed...)
+	}
+
+	ttl := time.Hour
+	if conf.Identity.CacheTTL != "" {
+		ttl, _ = time.ParseDuration(conf.Identity.CacheTTL)
+	}
+
+	return identity.NewDirectory(slack, ttl, people)
+}
+
+// Location returns the time zone of the team.
+func (team TeamConfig) Location() *time.Location {
+	if team.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(team.Timezone)
+	if err != nil {
+		return time.Local // rejected by Validate
+	}
+	return loc
+}
+
+// Cron returns the cron expression evaluated in the team's time zone. The
+// scheduler takes care of daylight saving changes.
+func (team TeamConfig) Cron(spec string) string {
+	if team.Timezone == "" || strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		return spec
+	}
+	return fmt.Sprintf("CRON_TZ=%s %s", team.Timezone, spec)
+}
+
+// SignoffItems returns the items of the team's sign-off checklist.
+func (team TeamConfig) SignoffItems() []string {
+	if len(team.Signoff) == 0 {
+		return DefaultSignoffItems
+	}
+	return team.Signoff
+}
+
+// JobTeam returns the team a job runs for.
+func (conf Config) JobTeam(job JobConfig) TeamConfig {
+	if job.Team != "" {
+		if team, ok := conf.Team(job.Team); ok {
+			return team
+		}
+	}
+	return conf.RotaTeams()[0]
+}
+
+// RotaTiers returns the configured tiers with defaults applied. A tier without
+// a prefix matches events starting with "<name>:" and covers a day.
+func (conf Config) RotaTiers() []rota.Tier {
+	if len(conf.Rota.Tiers) == 0 {
+		return rota.DefaultTiers
+	}
+
+	tiers := make([]rota.Tier, 0, len(conf.Rota.Tiers))
+	for _, t := range conf.Rota.Tiers {
+		if t.Prefix == "" {
+			t.Prefix = t.Name + ":"
+		}
+		if t.Window == "" {
+			t.Window = rota.Day
+		}
+		tiers = append(tiers, t)
+	}
+	return tiers
+}
+
+// RotaTeams returns the configured teams with defaults applied, or a single
+// default team when none are configured.
+func (conf Config) RotaTeams() []TeamConfig {
+	teams := conf.Teams
+	if len(teams) == 0 {
+		teams = []TeamConfig{{Name: rota.DefaultTeam}}
+	}
+
+	withDefaults := make([]TeamConfig, 0, len(teams))
+	for _, t := range teams {
+		if t.RotaCalendarId == "" {
+			t.RotaCalendarId = conf.Google.Calendar.RotaCalendarId
+		}
+		if t.OnHoliday == "" {
+			t.OnHoliday = OnHolidaySkip
+		}
+		if t.PostmortemFileId == "" {
+			t.PostmortemFileId = conf.Google.Drive.PostmortemFileId
+		}
+		if t.Schedule.StartOfDay == "" {
+			t.Schedule.StartOfDay = "30 6 * * 1-5"
+		}
+		if t.Schedule.BeforeEndOfDay == "" {
+			t.Schedule.BeforeEndOfDay = "30 14 * * 1-5"
+		}
+		if t.Schedule.EndOfDay == "" {
+			t.Schedule.EndOfDay = "30 15 * * 1-5"
+		}
+		withDefaults = append(withDefaults, t)
+	}
+	return withDefaults
+}
+
+// Team returns the team with the given name, ignoring case.
+func (conf Config) Team(name string) (TeamConfig, bool) {
+	for _, t := range conf.RotaTeams() {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return TeamConfig{}, false
+}
+
+// TeamForChannel returns the team the channel belongs to, falling back to
+// the first configured team.
+func (conf Config) TeamForChannel(channelID string) TeamConfig {
+	teams := conf.RotaTeams()
+	for _, t := range teams {
+		if t.QuestionsChannel == channelID {
+			return t
+		}
+		for _, c := range t.Channels {
+			if c == channelID {
+				return t
+			}
+		}
+	}
+	return teams[0]
+}
+
+// RotaCheck returns the rota check configuration with defaults applied.
+func (conf Config) RotaCheck() RotaCheckConfig {
+	check := conf.Rota.Check
+	if check.Schedule == "" {
+		check.Schedule = "0 9 * * 1-5"
+	}
+	if check.Days <= 0 {
+		check.Days = 14
+	}
+	return check
+}
+
+// RotaAbsence returns the absence scan configuration with defaults applied.
+func (conf Config) RotaAbsence() RotaAbsenceConfig {
+	absence := conf.Rota.Absence
+	if absence.Schedule == "" {
+		absence.Schedule = "0 9 * * 1-5"
+	}
+	if absence.Days <= 0 {
+		absence.Days = 7
+	}
+	return absence
+}
+
+// RotaMembers returns the team's roster with unavailability parsed.
+func (conf Config) RotaMembers(team TeamConfig) ([]rota.Member, error) {
+	members := make([]rota.Member, 0, len(team.Members))
+	for _, m := range team.Members {
+		member := rota.Member{Email: m.Email}
+		for _, expr := range m.Unavailable {
+			period, err := rota.ParsePeriod(expr, time.Now().In(team.Location()))
+			if err != nil {
+				return nil, fmt.Errorf("unavailability of %s: %w", m.Email, err)
+			}
+			member.Unavailable = append(member.Unavailable, period)
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// RotaStats returns the rota report configuration with defaults applied.
+func (conf Config) RotaStats() RotaStatsConfig {
+	stats := conf.Rota.Stats
+	if stats.Schedule == "" {
+		stats.Schedule = "0 9 1 * *"
+	}
+	return stats
+}
+
+// RotaReload returns how often a rota.Loader re-reads its source.
+func (conf Config) RotaReload() time.Duration {
+	if conf.Rota.Reload == "" {
+		return 5 * time.Minute
+	}
+	d, _ := time.ParseDuration(conf.Rota.Reload)
+	return d
+}
+
+// PostmortemStore creates the postmortem.Store selected by the configuration,
+// or nil when the google store has no credentials. The services are only used
+// by the google store and may be nil otherwise.
+func (conf Config) PostmortemStore(docsSrv *docs.Service, driveSrv *drive.Service) (postmortem.Store, error) {
+	switch conf.Postmortem.Store {
+	case "", PostmortemStoreGoogle:
+		if docsSrv == nil || driveSrv == nil {
+			return nil, nil
+		}
+		return postmortem.NewGoogleDocs(docsSrv, driveSrv), nil
+	case PostmortemStoreMarkdown:
+		templates, dir := conf.Postmortem.Templates, conf.Postmortem.Dir
+		if templates == "" {
+			templates = filepath.Join("postmortems", "templates")
+		}
+		if dir == "" {
+			dir = "postmortems"
+		}
+		return postmortem.NewMarkdown(templates, dir, conf.Postmortem.URL), nil
+	default:
+		return nil, fmt.Errorf("unknown postmortem store %q", conf.Postmortem.Store)
+	}
+}
+
+// PostmortemTemplate returns what the team's postmortems are created from in
+// the configured store.
+func (conf Config) PostmortemTemplate(team TeamConfig) string {
+	if conf.Postmortem.Store == PostmortemStoreMarkdown {
+		return team.PostmortemFile
+	}
+	return team.PostmortemFileId
+}
+
+// IncidentArchiveAfter returns how long the channel of a resolved incident is kept.
+func (conf Config) IncidentArchiveAfter() time.Duration {
+	if conf.Incident.ArchiveAfter == "" {
+		return 24 * time.Hour
+	}
+	d, _ := time.ParseDuration(conf.Incident.ArchiveAfter)
+	return d
+}
+
+// IncidentRoles returns the built-in incident roles followed by the configured ones.
+func (conf Config) IncidentRoles() []string {
+	roles := []string{incidentCommander, incidentComms, incidentScribe}
+	for _, r := range conf.Incident.Roles {
+		if r = strings.ToLower(r); !contains(roles, r) {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// IncidentSeverities returns the configured severities, or DefaultSeverities
+// paging the first or the first two rota tiers.
+func (conf Config) IncidentSeverities() []SeverityConfig {
+	if len(conf.Incident.Severities) > 0 {
+		return conf.Incident.Severities
+	}
+
+	var first, firstTwo []string
+	for i, t := range conf.RotaTiers() {
+		if i == 0 {
+			first = append(first, t.Name)
+		}
+		if i < 2 {
+			firstTwo = append(firstTwo, t.Name)
+		}
+	}
+	severities := make([]SeverityConfig, 0, len(DefaultSeverities))
+	for _, s := range DefaultSeverities {
+		s.Page = first
+		if s.Postmortem {
+			s.Page = firstTwo
+		}
+		severities = append(severities, s)
+	}
+	return severities
+}
+
+// DefaultSeverities are used when no severities are configured, the ones
+// with a mandatory postmortem page the first two tiers, the others the first.
+var DefaultSeverities = []SeverityConfig{
+	{Name: "SEV1", Description: "Critical, customers can't use the product", UpdateEvery: "30m", Postmortem: true, PostmortemDue: 5},
+	{Name: "SEV2", Description: "Major, a core feature is down or degraded for many customers", UpdateEvery: "1h", Postmortem: true, PostmortemDue: 10},
+	{Name: "SEV3", Description: "Minor, some customers are affected and there is a workaround", UpdateEvery: "4h"},
+	{Name: "SEV4", Description: "Low, no customer impact yet"},
+}
+
+// IncidentSeverity returns the severity of the name, matched case-insensitively.
+// The number alone is short for a severity, e.g. 1 for SEV1.
+func (conf Config) IncidentSeverity(name string) (SeverityConfig, bool) {
+	for _, s := range conf.IncidentSeverities() {
+		if strings.EqualFold(s.Name, name) {
+			return s, true
+		}
+	}
+	for _, s := range conf.IncidentSeverities() {
+		if name != "" && strings.TrimLeftFunc(s.Name, unicode.IsLetter) == name {
+			return s, true
+		}
+	}
+	return SeverityConfig{}, false
+}
+
+// UsesGoogleDocs reports whether credentials for Google Docs and Drive, which
+// the google postmortem store needs, are configured.
+func (conf Config) UsesGoogleDocs() bool {
+	return conf.Google.Docs.Service.Type != "" && conf.Google.Drive.Service.Type != ""
+}
+
+// UsesGoogleCalendar reports whether the bot needs a Google Calendar service,
+// either to read the rota or people's calendars.
+func (conf Config) UsesGoogleCalendar() bool {
+	for _, h := range conf.Holidays {
+		if h.CalendarId != "" {
+			return true
+		}
+	}
+	return conf.Rota.Provider == "" || conf.Rota.Provider == RotaProviderGoogle || conf.Google.Calendar.Service.Type != ""
+}
+
+// validSchedule checks a cron expression with the parser of the cron module,
+// so mistakes show up when the configuration is loaded
+func validSchedule(spec string) error {
+	if _, err := cron.Parser.Parse(spec); err != nil {
+		return fmt.Errorf("invalid schedule %q: %v", spec, err)
+	}
+	return nil
+}
+
 func (conf Config) Validate() error {
 	//if conf.HTTPListen == "" {
 	//	return errors.New("missing HTTP listen address")
 	//}
+	if conf.Rota.Provider == RotaProviderFile && conf.Rota.File == "" {
+		return errors.New("missing rota file for the file rota provider")
+	}
+	switch conf.Memory.Type {
+	case "":
+	case memory.TypeFile, memory.TypeBolt:
+		if conf.Memory.Path == "" {
+			return fmt.Errorf("missing path for the %s memory", conf.Memory.Type)
+		}
+	default:
+		return fmt.Errorf("unknown memory type %q", conf.Memory.Type)
+	}
+	for _, r := range conf.Incident.Roles {
+		if r == "" || strings.ContainsAny(r, " \t") {
+			return fmt.Errorf("invalid incident role %q, roles are single words", r)
+		}
+	}
+	if conf.Incident.ArchiveAfter != "" {
+		if d, err := time.ParseDuration(conf.Incident.ArchiveAfter); err != nil || d <= 0 {
+			return fmt.Errorf("invalid incident archive_after %q", conf.Incident.ArchiveAfter)
+		}
+	}
+	var severities []string
+	for _, s := range conf.Incident.Severities {
+		if s.Name == "" || strings.ContainsAny(s.Name, " \t") {
+			return fmt.Errorf("invalid incident severity %q, severities are single words", s.Name)
+		}
+		if contains(severities, strings.ToLower(s.Name)) {
+			return fmt.Errorf("duplicate incident severity %q", s.Name)
+		}
+		severities = append(severities, strings.ToLower(s.Name))
+		for _, tier := range s.Page {
+			if !conf.hasTier(tier) {
+				return fmt.Errorf("severity %s pages unknown tier %q", s.Name, tier)
+			}
+		}
+		if s.UpdateEvery != "" {
+			if d, err := time.ParseDuration(s.UpdateEvery); err != nil || d <= 0 {
+				return fmt.Errorf("invalid update_every %q of severity %s", s.UpdateEvery, s.Name)
+			}
+		}
+		if s.PostmortemDue < 0 {
+			return fmt.Errorf("invalid postmortem_due %d of severity %s", s.PostmortemDue, s.Name)
+		}
+	}
+	switch conf.Postmortem.Store {
+	case "", PostmortemStoreGoogle, PostmortemStoreMarkdown:
+	default:
+		return fmt.Errorf("unknown postmortem store %q", conf.Postmortem.Store)
+	}
+	if conf.Identity.CacheTTL != "" {
+		if d, err := time.ParseDuration(conf.Identity.CacheTTL); err != nil || d <= 0 {
+			return fmt.Errorf("invalid identity cache_ttl %q", conf.Identity.CacheTTL)
+		}
+	}
+	if conf.Rota.Reload != "" {
+		if d, err := time.ParseDuration(conf.Rota.Reload); err != nil || d <= 0 {
+			return fmt.Errorf("invalid rota reload interval %q", conf.Rota.Reload)
+		}
+	}
+
+	regions := map[string]bool{}
+	for _, h := range conf.Holidays {
+		region := strings.ToLower(h.Region)
+		if region == "" {
+			return errors.New("holiday sources must have a region")
+		}
+		if regions[region] {
+			return fmt.Errorf("duplicate holiday region %q", h.Region)
+		}
+		regions[region] = true
+
+		if (h.CalendarId == "") == (h.File == "") {
+			return fmt.Errorf("holiday region %q needs either a calendar_id or a file", h.Region)
+		}
+	}
+
+	teams := map[string]bool{}
+	for _, t := range conf.RotaTeams() {
+		name := strings.ToLower(t.Name)
+		if name == "" {
+			return errors.New("teams must have a name")
+		}
+		if teams[name] {
+			return fmt.Errorf("duplicate team %q", t.Name)
+		}
+		teams[name] = true
+
+		for _, l := range t.Runbooks {
+			if l.Title == "" || l.Url == "" {
+				return fmt.Errorf("team %q: runbooks must have a title and a url", t.Name)
+			}
+		}
+		if t.Timezone != "" {
+			if _, err := time.LoadLocation(t.Timezone); err != nil {
+				return fmt.Errorf("team %q: invalid timezone %q", t.Name, t.Timezone)
+			}
+		}
+		for _, spec := range []string{t.Schedule.StartOfDay, t.Schedule.BeforeEndOfDay, t.Schedule.EndOfDay,
+			conf.RotaCheck().Schedule, conf.RotaAbsence().Schedule, conf.RotaStats().Schedule} {
+			if err := validSchedule(t.Cron(spec)); err != nil {
+				return fmt.Errorf("team %q: %w", t.Name, err)
+			}
+		}
+		if t.Region != "" && !regions[strings.ToLower(t.Region)] {
+			return fmt.Errorf("team %q: unknown holiday region %q", t.Name, t.Region)
+		}
+		if t.OnHoliday != OnHolidaySkip && t.OnHoliday != OnHolidayRun {
+			return fmt.Errorf("team %q: on_holiday must be %s or %s", t.Name, OnHolidaySkip, OnHolidayRun)
+		}
+
+		for _, g := range t.Usergroups {
+			if g.Id == "" {
+				return fmt.Errorf("team %q: usergroups must have an id", t.Name)
+			}
+			if g.Tier != "" && !conf.hasTier(g.Tier) {
+				return fmt.Errorf("team %q: usergroup %s has unknown tier %q", t.Name, g.Id, g.Tier)
+			}
+		}
+
+		if conf.Rota.Provider == RotaProviderICS && t.RotaFile == "" {
+			return fmt.Errorf("team %q: missing rota_file for the ics rota provider", t.Name)
+		}
+
+		if _, err := conf.RotaMembers(t); err != nil {
+			return fmt.Errorf("team %q: %w", t.Name, err)
+		}
+	}
+
+	for _, name := range messageNames() {
+		if path := conf.Messages.File(name); path != "" {
+			if _, err := readMessageTemplate(name, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	jobs := map[string]bool{}
+	for _, j := range conf.Jobs {
+		if j.Name == "" || j.Schedule == "" {
+			return errors.New("jobs must have a name and a schedule")
+		}
+		if jobs[j.Name] {
+			return fmt.Errorf("duplicate job %q", j.Name)
+		}
+		jobs[j.Name] = true
+
+		if _, ok := jobActions[j.Action]; !ok {
+			return fmt.Errorf("job %q: unknown action %q", j.Name, j.Action)
+		}
+		if j.Action == jobActionMessage && j.Text == "" {
+			return fmt.Errorf("job %q: missing text to post", j.Name)
+		}
+		if _, ok := conf.Team(j.Team); j.Team != "" && !ok {
+			return fmt.Errorf("job %q: unknown team %q", j.Name, j.Team)
+		}
+		if err := validSchedule(conf.JobTeam(j).Cron(j.Schedule)); err != nil {
+			return fmt.Errorf("job %q: %w", j.Name, err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, t := range conf.RotaTiers() {
+		if t.Name == "" {
+			return errors.New("rota tiers must have a name")
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("duplicate rota tier %q", t.Name)
+		}
+		seen[t.Name] = true
+
+		if err := t.Window.Validate(); err != nil {
+			return fmt.Errorf("rota tier %q: %w", t.Name, err)
+		}
+	}
+
 	return nil
 }
+
+// hasTier reports whether a tier of the name is configured.
+func (conf Config) hasTier(name string) bool {
+	for _, t := range conf.RotaTiers() {
+		if strings.EqualFold(t.Name, name) {
+			return true
+		}
+	}
+	return false
+}
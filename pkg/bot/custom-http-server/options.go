@@ -3,6 +3,7 @@ package httpserver
 import (
 	"crypto/tls"
 	"errors"
+	"net/http"
 	"time"
 
 	"github.com/go-joe/joe"
@@ -20,6 +21,7 @@ type config struct {
 	tlsConf           *tls.Config
 	certFile, keyFile string
 	trustedHeader     string
+	handlers          map[string]http.Handler
 }
 
 func newConf(listenAddr string, joeConf *joe.Config, opts []Option) (config, error) {
@@ -108,3 +110,15 @@ func WithTrustedHeader(header string) Option {
 		return nil
 	}
 }
+
+// WithHandler serves requests whose path matches pattern, as understood by
+// http.ServeMux, with the given handler instead of emitting them as events.
+func WithHandler(pattern string, handler http.Handler) Option {
+	return func(conf *config) error {
+		if conf.handlers == nil {
+			conf.handlers = map[string]http.Handler{}
+		}
+		conf.handlers[pattern] = handler
+		return nil
+	}
+}
@@ -76,9 +76,15 @@ func newServer(conf config, events joe.EventEmitter) *server {
 		conf:   conf,
 	}
 
+	mux := http.NewServeMux()
+	for pattern, handler := range conf.handlers {
+		mux.Handle(pattern, handler)
+	}
+	mux.HandleFunc("/", srv.HTTPHandler)
+
 	srv.http = &http.Server{
 		Addr:         conf.listenAddr,
-		Handler:      http.HandlerFunc(srv.HTTPHandler),
+		Handler:      mux,
 		ErrorLog:     zap.NewStdLog(conf.logger),
 		TLSConfig:    conf.tlsConf,
 		ReadTimeout:  conf.readTimeout,
@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dombo/hiberBot/pkg/bot/identity"
+	"github.com/dombo/hiberBot/pkg/bot/rota"
+	"github.com/jinzhu/now"
+)
+
+// rotaFeed serves the upcoming shifts of a team, or of one person in a team,
+// as an iCalendar feed people can subscribe to in their calendar client:
+//
+//	GET /rota/<team>.ics?token=<token>
+//	GET /rota/<team>/<email>.ics?token=<token>
+//
+// The feeds list the shifts as they apply, without the parts of regular shifts
+// that an override replaces. A person's feed includes the shifts of any of
+// their emails. The feeds are only served when a token is configured.
+type rotaFeed struct {
+	conf       Config
+	rota       rota.Provider
+	identities *identity.Directory
+}
+
+func (f *rotaFeed) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	feedConf := f.conf.Rota.Feed
+	token := []byte(req.URL.Query().Get("token"))
+	if feedConf.Token == "" || subtle.ConstantTimeCompare(token, []byte(feedConf.Token)) != 1 {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/rota/")
+	if !strings.HasSuffix(path, ".ics") {
+		http.NotFound(w, req)
+		return
+	}
+	parts := strings.SplitN(strings.TrimSuffix(path, ".ics"), "/", 2)
+
+	team, ok := f.conf.Team(parts[0])
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	days := feedConf.Days
+	if days <= 0 {
+		days = 90
+	}
+	from := now.With(time.Now().In(team.Location())).BeginningOfDay()
+	shifts, err := f.rota.Shifts(team.Name, from, from.AddDate(0, 0, days))
+	if err != nil {
+		http.Error(w, "failed to read the rota", http.StatusInternalServerError)
+		return
+	}
+	shifts = rota.Resolve(shifts)
+
+	name := fmt.Sprintf("%s on-call rota", team.Name)
+	if len(parts) == 2 {
+		var mine []rota.Shift
+		for _, s := range shifts {
+			if f.identities.Same(s.Email, parts[1]) {
+				mine = append(mine, s)
+			}
+		}
+		shifts = mine
+		name = fmt.Sprintf("%s on-call shifts of %s", team.Name, parts[1])
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s.ics"`, team.Name))
+	if err := rota.WriteICS(w, name, shifts); err != nil {
+		http.Error(w, "failed to write the calendar", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,258 @@
+// Package ical reads the events of iCalendar (RFC 5545) files, including the
+// common forms of recurring events.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Event is a VEVENT of a calendar. A recurring event has an RRule, use
+// Occurrences to expand it.
+type Event struct {
+	UID          string
+	Summary      string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	Attendees    []string // email addresses
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID time.Time // set on events that replace one occurrence of a recurring event
+	Cancelled    bool
+
+	duration string // DURATION, resolved once DTSTART is known
+}
+
+// property is a content line: NAME;PARAM=VALUE:VALUE
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads every VEVENT from r. Dates and times without a time zone are
+// read in loc.
+func Parse(r io.Reader, loc *time.Location) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var current *Event
+	depth := 0 // nesting inside the current VEVENT, e.g. a VALARM
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		prop, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		switch {
+		case prop.name == "BEGIN" && prop.value == "VEVENT":
+			current = &Event{}
+			depth = 0
+			continue
+		case current == nil:
+			continue
+		case prop.name == "BEGIN":
+			depth++
+			continue
+		case prop.name == "END" && depth > 0:
+			depth--
+			continue
+		case prop.name == "END" && prop.value == "VEVENT":
+			if current.End.IsZero() && current.duration != "" {
+				days, d, err := parseDuration(current.duration)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", i+1, err)
+				}
+				current.End = current.Start.AddDate(0, 0, days).Add(d)
+			}
+			if current.End.IsZero() {
+				current.End = current.Start
+				if current.AllDay {
+					current.End = current.Start.AddDate(0, 0, 1)
+				}
+			}
+			events = append(events, *current)
+			current = nil
+			continue
+		case depth > 0:
+			continue
+		}
+
+		if err := current.apply(prop, loc); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+
+	return events, nil
+}
+
+func (e *Event) apply(prop property, loc *time.Location) error {
+	var err error
+	switch prop.name {
+	case "UID":
+		e.UID = prop.value
+	case "SUMMARY":
+		e.Summary = unescapeText(prop.value)
+	case "DTSTART":
+		e.Start, e.AllDay, err = parseTime(prop, loc)
+	case "DTEND":
+		e.End, _, err = parseTime(prop, loc)
+	case "DURATION":
+		e.duration = prop.value
+	case "ATTENDEE":
+		email := prop.value
+		if strings.HasPrefix(strings.ToLower(email), "mailto:") {
+			email = email[len("mailto:"):]
+		}
+		e.Attendees = append(e.Attendees, email)
+	case "RRULE":
+		e.RRule = prop.value
+	case "EXDATE":
+		for _, v := range strings.Split(prop.value, ",") {
+			t, _, err := parseTime(property{name: prop.name, params: prop.params, value: v}, loc)
+			if err != nil {
+				return err
+			}
+			e.ExDates = append(e.ExDates, t)
+		}
+	case "RECURRENCE-ID":
+		e.RecurrenceID, _, err = parseTime(prop, loc)
+	case "STATUS":
+		e.Cancelled = prop.value == "CANCELLED"
+	}
+	return err
+}
+
+// unfold joins the continuation lines of r, which start with a space or tab.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func parseProperty(line string) (property, error) {
+	// The value starts at the first colon that is not inside a quoted parameter.
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, fmt.Errorf("missing value in %q", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := property{
+		name:   strings.ToUpper(parts[0]),
+		params: map[string]string{},
+		value:  line[colon+1:],
+	}
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) == 2 {
+			prop.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return prop, nil
+}
+
+// parseTime parses a DATE or DATE-TIME value, honouring the TZID parameter.
+// Floating times and dates are read in loc. A TZID that isn't an IANA time
+// zone, such as the Windows names in Outlook exports, is an error rather than
+// a guess.
+func parseTime(prop property, loc *time.Location) (time.Time, bool, error) {
+	if tzid := prop.params["TZID"]; tzid != "" {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown time zone %q in %s, use an IANA name such as Europe/London", tzid, prop.name)
+		}
+		loc = l
+	}
+
+	value := strings.TrimSpace(prop.value)
+	switch {
+	case prop.params["VALUE"] == "DATE" || len(value) == len("20060102"):
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	case strings.HasSuffix(value, "Z"):
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	default:
+		t, err := time.ParseInLocation("20060102T150405", value, loc)
+		return t, false, err
+	}
+}
+
+// parseDuration parses the RFC 5545 duration format, e.g. P1W, P1D or PT8H30M.
+// Weeks and days are returned as calendar days so they keep the time of day
+// across daylight saving changes.
+func parseDuration(value string) (int, time.Duration, error) {
+	sign := 1
+	switch {
+	case strings.HasPrefix(value, "-"):
+		sign = -1
+		value = value[1:]
+	case strings.HasPrefix(value, "+"):
+		value = value[1:]
+	}
+	if !strings.HasPrefix(value, "P") {
+		return 0, 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var days int
+	var d time.Duration
+	var n int
+	inTime := false
+	for _, c := range value[1:] {
+		switch {
+		case c >= '0' && c <= '9':
+			n = n*10 + int(c-'0')
+			continue
+		case c == 'T':
+			inTime = true
+		case c == 'W' && !inTime:
+			days += n * 7
+		case c == 'D' && !inTime:
+			days += n
+		case c == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case c == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case c == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		default:
+			return 0, 0, fmt.Errorf("invalid duration %q", value)
+		}
+		n = 0
+	}
+	return sign * days, time.Duration(sign) * d, nil
+}
+
+func unescapeText(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
@@ -0,0 +1,129 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// calendar wraps the events in a VCALENDAR with CRLF line endings
+func calendar(events ...string) string {
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0"}
+	for _, e := range events {
+		lines = append(lines, "BEGIN:VEVENT")
+		lines = append(lines, strings.Split(strings.TrimSpace(e), "\n")...)
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func TestParse(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("no time zone database", err)
+	}
+
+	events, err := Parse(strings.NewReader(calendar(`
+UID:1
+SUMMARY:L1: alice\, on call
+DTSTART;TZID=Europe/London:20200615T090000
+DURATION:P1DT2H
+ATTENDEE;CN="Alice: SRE";ROLE=REQ-PARTICIPANT:mailto:alice@example.com
+ATTENDEE:MAILTO:bob@example.com
+BEGIN:VALARM
+SUMMARY:not the event
+END:VALARM`, `
+UID:2
+SUMMARY:Holiday
+DTSTART;VALUE=DATE:20200618`, `
+UID:3
+SUMMARY:Folded
+ summary
+DTSTART:20200618T100000Z
+DTEND:20200618T120000Z
+STATUS:CANCELLED`)), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+
+	tests := []struct {
+		event   Event
+		summary string
+		start   time.Time
+		end     time.Time
+		allDay  bool
+	}{
+		{events[0], "L1: alice, on call", time.Date(2020, 6, 15, 9, 0, 0, 0, london), time.Date(2020, 6, 16, 11, 0, 0, 0, london), false},
+		{events[1], "Holiday", time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC), time.Date(2020, 6, 19, 0, 0, 0, 0, time.UTC), true},
+		{events[2], "Foldedsummary", time.Date(2020, 6, 18, 10, 0, 0, 0, time.UTC), time.Date(2020, 6, 18, 12, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		e := tt.event
+		if e.Summary != tt.summary || !e.Start.Equal(tt.start) || !e.End.Equal(tt.end) || e.AllDay != tt.allDay {
+			t.Errorf("event %s = %q %v - %v all day %v, want %q %v - %v all day %v",
+				e.UID, e.Summary, e.Start, e.End, e.AllDay, tt.summary, tt.start, tt.end, tt.allDay)
+		}
+	}
+	if got := strings.Join(events[0].Attendees, ","); got != "alice@example.com,bob@example.com" {
+		t.Errorf("attendees = %s", got)
+	}
+	if !events[2].Cancelled {
+		t.Error("cancelled event isn't Cancelled")
+	}
+}
+
+func TestParseFloatingTimes(t *testing.T) {
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	if err != nil {
+		t.Skip("no time zone database", err)
+	}
+
+	events, err := Parse(strings.NewReader(calendar(`
+UID:1
+DTSTART;VALUE=DATE:20200618`)), sydney)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2020, 6, 18, 0, 0, 0, 0, sydney); !events[0].Start.Equal(want) {
+		t.Errorf("start = %v, want %v", events[0].Start, want)
+	}
+}
+
+func TestParseUnknownTimeZone(t *testing.T) {
+	_, err := Parse(strings.NewReader(calendar(`
+UID:1
+DTSTART;TZID=W. Europe Standard Time:20200615T090000`)), time.UTC)
+	if err == nil || !strings.Contains(err.Error(), "W. Europe Standard Time") {
+		t.Errorf("Parse() = %v, want an error naming the time zone", err)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		days  int
+		d     time.Duration
+	}{
+		{"P1W", 7, 0},
+		{"P2D", 2, 0},
+		{"PT8H30M", 0, 8*time.Hour + 30*time.Minute},
+		{"P1DT12H", 1, 12 * time.Hour},
+		{"-PT15M", 0, -15 * time.Minute},
+	}
+	for _, tt := range tests {
+		days, d, err := parseDuration(tt.value)
+		if err != nil || days != tt.days || d != tt.d {
+			t.Errorf("parseDuration(%q) = %d, %v, %v, want %d, %v", tt.value, days, d, err, tt.days, tt.d)
+		}
+	}
+
+	for _, value := range []string{"1D", "PT1D", "P1H", "P1X"} {
+		if _, _, err := parseDuration(value); err == nil {
+			t.Errorf("parseDuration(%q) didn't fail", value)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxOccurrences stops the expansion of rules that never end, it allows for a
+// daily event repeating for a few centuries.
+const maxOccurrences = 100000
+
+// rule is the supported subset of an RRULE: FREQ, INTERVAL, COUNT, UNTIL and
+// BYDAY without ordinals for weekly rules.
+type rule struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+	byDay    []time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseRule parses an RRULE, an UNTIL without a time zone is read in loc.
+func parseRule(value string, loc *time.Location) (rule, error) {
+	r := rule{interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return r, fmt.Errorf("invalid recurrence rule %q", value)
+		}
+
+		var err error
+		switch strings.ToUpper(kv[0]) {
+		case "FREQ":
+			r.freq = strings.ToUpper(kv[1])
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(kv[1])
+		case "COUNT":
+			r.count, err = strconv.Atoi(kv[1])
+		case "UNTIL":
+			r.until, _, err = parseTime(property{params: map[string]string{}, value: kv[1]}, loc)
+		case "BYDAY":
+			for _, d := range strings.Split(kv[1], ",") {
+				wd, ok := weekdays[strings.ToUpper(d)]
+				if !ok {
+					return r, fmt.Errorf("unsupported BYDAY %q in recurrence rule", d)
+				}
+				r.byDay = append(r.byDay, wd)
+			}
+		case "WKST":
+		default:
+			return r, fmt.Errorf("unsupported %s in recurrence rule", kv[0])
+		}
+		if err != nil {
+			return r, fmt.Errorf("invalid %s in recurrence rule: %w", kv[0], err)
+		}
+	}
+
+	switch r.freq {
+	case "DAILY", "MONTHLY", "YEARLY":
+		if len(r.byDay) > 0 {
+			return r, fmt.Errorf("BYDAY is only supported for weekly recurrence rules")
+		}
+	case "WEEKLY":
+	default:
+		return r, fmt.Errorf("unsupported frequency %q in recurrence rule", r.freq)
+	}
+	if r.interval < 1 {
+		return r, fmt.Errorf("invalid interval %d in recurrence rule", r.interval)
+	}
+	return r, nil
+}
+
+// starts returns the starts in the n-th period of the rule after start. A
+// period without the day of start, e.g. the 31st in a monthly rule, has none,
+// as RFC 5545 skips invalid dates.
+func (r rule) starts(start time.Time, n int) []time.Time {
+	step := n * r.interval
+	switch r.freq {
+	case "DAILY":
+		return []time.Time{start.AddDate(0, 0, step)}
+	case "MONTHLY":
+		return addMonths(start, step)
+	case "YEARLY":
+		return addMonths(start, 12*step)
+	}
+
+	week := start.AddDate(0, 0, 7*step)
+	if len(r.byDay) == 0 {
+		return []time.Time{week}
+	}
+
+	// BYDAY days are counted from the Monday of the week, the default WKST.
+	monday := week.AddDate(0, 0, -((int(week.Weekday()) + 6) % 7))
+	var starts []time.Time
+	for _, wd := range r.byDay {
+		starts = append(starts, monday.AddDate(0, 0, (int(wd)+6)%7))
+	}
+	// The first occurrence is always the event itself, even when it isn't
+	// on one of the days.
+	if n == 0 && !contains(starts, start) {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	return starts
+}
+
+// addMonths returns t the number of months later, or nothing when that month
+// doesn't have the day of t.
+func addMonths(t time.Time, months int) []time.Time {
+	later := time.Date(t.Year(), t.Month()+time.Month(months), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if later.Day() != t.Day() {
+		return nil
+	}
+	return []time.Time{later}
+}
+
+// Occurrences returns the instances of the event that overlap [from, to). A
+// non-recurring event is its only occurrence. Instances listed in ExDates or
+// in replaced are left out, replaced holds the RECURRENCE-IDs of the events
+// that override single instances of this one.
+func (e Event) Occurrences(from, to time.Time, replaced []time.Time) ([]Event, error) {
+	if e.Cancelled {
+		return nil, nil
+	}
+	if e.RRule == "" {
+		if e.Start.Before(to) && e.End.After(from) {
+			return []Event{e}, nil
+		}
+		return nil, nil
+	}
+
+	r, err := parseRule(e.RRule, e.Start.Location())
+	if err != nil {
+		return nil, err
+	}
+
+	// Occurrences keep the length of the event in calendar days and time of
+	// day, so shifts that cross a daylight saving change still end on time.
+	days := int(dateOf(e.End.In(e.Start.Location())).Sub(dateOf(e.Start)).Hours()+12) / 24
+	clock := e.End.Sub(e.Start.AddDate(0, 0, days))
+	end := func(start time.Time) time.Time { return start.AddDate(0, 0, days).Add(clock) }
+	skip := append(append([]time.Time(nil), e.ExDates...), replaced...)
+
+	var occurrences []Event
+	seen := 0
+	for n := 0; n < maxOccurrences; n++ {
+		starts := r.starts(e.Start, n)
+		if len(starts) == 0 {
+			continue
+		}
+		if !starts[0].Before(to) {
+			break
+		}
+
+		for _, start := range starts {
+			if start.Before(e.Start) {
+				continue
+			}
+			if !r.until.IsZero() && start.After(r.until) {
+				return occurrences, nil
+			}
+			seen++
+			if r.count > 0 && seen > r.count {
+				return occurrences, nil
+			}
+			if contains(skip, start) || !start.Before(to) || !end(start).After(from) {
+				continue
+			}
+
+			instance := e
+			instance.RRule = ""
+			instance.ExDates = nil
+			instance.Start = start
+			instance.End = end(start)
+			occurrences = append(occurrences, instance)
+		}
+	}
+
+	return occurrences, nil
+}
+
+// Expand returns every occurrence of the events overlapping [from, to),
+// applying the events that replace single instances of recurring ones.
+func Expand(events []Event, from, to time.Time) ([]Event, []error) {
+	replaced := map[string][]time.Time{}
+	for _, e := range events {
+		if !e.RecurrenceID.IsZero() {
+			replaced[e.UID] = append(replaced[e.UID], e.RecurrenceID)
+		}
+	}
+
+	var occurrences []Event
+	var errs []error
+	for _, e := range events {
+		var r []time.Time
+		if e.RecurrenceID.IsZero() {
+			r = replaced[e.UID]
+		}
+
+		o, err := e.Occurrences(from, to, r)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("event %q: %w", e.Summary, err))
+			continue
+		}
+		occurrences = append(occurrences, o...)
+	}
+	return occurrences, errs
+}
+
+func contains(times []time.Time, t time.Time) bool {
+	for _, c := range times {
+		if c.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// dateOf returns midnight UTC of the calendar date of t, to count days
+// between two times regardless of daylight saving.
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,263 @@
+package ical
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpand(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("no time zone database", err)
+	}
+	utc := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2020, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	from, to := utc(time.January, 1, 0), time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		events []string
+		from   time.Time
+		to     time.Time
+		starts []time.Time
+		end    time.Duration // of the first occurrence
+	}{
+		{
+			name: "single event",
+			events: []string{`
+UID:1
+DTSTART:20200615T090000Z
+DTEND:20200615T170000Z`},
+			from:   from,
+			to:     to,
+			starts: []time.Time{utc(time.June, 15, 9)},
+			end:    8 * time.Hour,
+		},
+		{
+			name: "count",
+			events: []string{`
+UID:1
+DTSTART:20200615T090000Z
+DURATION:PT8H
+RRULE:FREQ=DAILY;COUNT=3`},
+			from:   from,
+			to:     to,
+			starts: []time.Time{utc(time.June, 15, 9), utc(time.June, 16, 9), utc(time.June, 17, 9)},
+			end:    8 * time.Hour,
+		},
+		{
+			name: "until is inclusive",
+			events: []string{`
+UID:1
+DTSTART:20200615T090000Z
+DTEND:20200615T100000Z
+RRULE:FREQ=WEEKLY;INTERVAL=2;UNTIL=20200713T090000Z`},
+			from:   from,
+			to:     to,
+			starts: []time.Time{utc(time.June, 15, 9), utc(time.June, 29, 9), utc(time.July, 13, 9)},
+			end:    time.Hour,
+		},
+		{
+			name: "only occurrences in the period",
+			events: []string{`
+UID:1
+DTSTART:20200615T090000Z
+DTEND:20200615T100000Z
+RRULE:FREQ=DAILY`},
+			from:   utc(time.June, 20, 0),
+			to:     utc(time.June, 22, 0),
+			starts: []time.Time{utc(time.June, 20, 9), utc(time.June, 21, 9)},
+			end:    time.Hour,
+		},
+		{
+			name: "exdate",
+			events: []string{`
+UID:1
+DTSTART:20200615T090000Z
+DTEND:20200615T100000Z
+RRULE:FREQ=DAILY;COUNT=4
+EXDATE:20200616T090000Z,20200617T090000Z`},
+			from:   from,
+			to:     to,
+			starts: []time.Time{utc(time.June, 15, 9), utc(time.June, 18, 9)},
+			end:    time.Hour,
+		},
+		{
+			name: "recurrence-id overrides an instance",
+			events: []string{`
+UID:1
+DTSTART:20200615T090000Z
+DTEND:20200615T100000Z
+RRULE:FREQ=DAILY;COUNT=3`, `
+UID:1
+RECURRENCE-ID:20200616T090000Z
+DTSTART:20200616T140000Z
+DTEND:20200616T150000Z`},
+			from:   from,
+			to:     to,
+			starts: []time.Time{utc(time.June, 15, 9), utc(time.June, 16, 14), utc(time.June, 17, 9)},
+			end:    time.Hour,
+		},
+		{
+			name: "cancelled instance",
+			events: []string{`
+UID:1
+DTSTART:20200615T090000Z
+DTEND:20200615T100000Z
+RRULE:FREQ=DAILY;COUNT=2`, `
+UID:1
+RECURRENCE-ID:20200615T090000Z
+DTSTART:20200615T090000Z
+STATUS:CANCELLED`},
+			from:   from,
+			to:     to,
+			starts: []time.Time{utc(time.June, 16, 9)},
+			end:    time.Hour,
+		},
+		{
+			name: "monthly on the 31st skips shorter months",
+			events: []string{`
+UID:1
+DTSTART:20200131T090000Z
+DTEND:20200131T100000Z
+RRULE:FREQ=MONTHLY;COUNT=4`},
+			from:   from,
+			to:     to,
+			starts: []time.Time{utc(time.January, 31, 9), utc(time.March, 31, 9), utc(time.May, 31, 9), utc(time.July, 31, 9)},
+			end:    time.Hour,
+		},
+		{
+			name: "yearly on the 29th of February",
+			events: []string{`
+UID:1
+DTSTART;VALUE=DATE:20200229
+RRULE:FREQ=YEARLY`},
+			from:   from,
+			to:     time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+			starts: []time.Time{utc(time.February, 29, 0), time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+			end:    24 * time.Hour,
+		},
+		{
+			name: "byday",
+			events: []string{`
+UID:1
+DTSTART:20200615T090000Z
+DTEND:20200615T100000Z
+RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4`},
+			from:   from,
+			to:     to,
+			starts: []time.Time{utc(time.June, 15, 9), utc(time.June, 17, 9), utc(time.June, 22, 9), utc(time.June, 24, 9)},
+			end:    time.Hour,
+		},
+		{
+			name: "byday keeps a start on another day",
+			events: []string{`
+UID:1
+DTSTART:20200616T090000Z
+DTEND:20200616T100000Z
+RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3`},
+			from:   from,
+			to:     to,
+			starts: []time.Time{utc(time.June, 16, 9), utc(time.June, 17, 9), utc(time.June, 22, 9)},
+			end:    time.Hour,
+		},
+		{
+			name: "weekly across daylight saving keeps the time of day",
+			events: []string{`
+UID:1
+DTSTART;TZID=Europe/London:20200323T090000
+DTEND;TZID=Europe/London:20200324T090000
+RRULE:FREQ=WEEKLY;COUNT=2`},
+			from: from,
+			to:   to,
+			starts: []time.Time{
+				time.Date(2020, time.March, 23, 9, 0, 0, 0, london),
+				time.Date(2020, time.March, 30, 9, 0, 0, 0, london),
+			},
+			end: 24 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		events, err := Parse(strings.NewReader(calendar(tt.events...)), time.UTC)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		occurrences, errs := Expand(events, tt.from, tt.to)
+		if len(errs) > 0 {
+			t.Errorf("%s: %v", tt.name, errs)
+			continue
+		}
+		var starts []time.Time
+		for _, o := range occurrences {
+			starts = append(starts, o.Start)
+		}
+		sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+		if !equalTimes(starts, tt.starts) {
+			t.Errorf("%s: starts = %v, want %v", tt.name, starts, tt.starts)
+			continue
+		}
+		if len(occurrences) > 0 {
+			if d := occurrences[0].End.Sub(occurrences[0].Start); d != tt.end {
+				t.Errorf("%s: first occurrence lasts %v, want %v", tt.name, d, tt.end)
+			}
+		}
+	}
+}
+
+func TestExpandDaylightSavingEnd(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("no time zone database", err)
+	}
+
+	// A daily 09:00 to 09:00 shift is 23 hours long on the day the clocks go forward
+	events, err := Parse(strings.NewReader(calendar(`
+UID:1
+DTSTART;TZID=Europe/London:20200328T090000
+DTEND;TZID=Europe/London:20200329T090000
+RRULE:FREQ=DAILY;COUNT=3`)), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	occurrences, _ := Expand(events, time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC))
+	if len(occurrences) != 3 {
+		t.Fatalf("got %d occurrences, want 3", len(occurrences))
+	}
+	for _, o := range occurrences {
+		if start, end := o.Start.In(london), o.End.In(london); start.Hour() != 9 || end.Hour() != 9 {
+			t.Errorf("occurrence is %v - %v, want 09:00 to 09:00", start, end)
+		}
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	for _, value := range []string{
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTH=1",
+		"FREQ",
+	} {
+		if _, err := parseRule(value, time.UTC); err == nil {
+			t.Errorf("parseRule(%q) didn't fail", value)
+		}
+	}
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
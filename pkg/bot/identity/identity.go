@@ -0,0 +1,219 @@
+// Package identity maps the emails people have on the rota to their Slack
+// users, allowing for people whose Slack email differs or who have several
+// emails, and caches the lookups.
+package identity
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	slackAPI "github.com/slack-go/slack"
+)
+
+// Person ties the emails of someone to their Slack user. Email is the one
+// written to the rota, Aliases are other emails that may appear on it. When
+// SlackID is empty the Slack user is looked up by the emails in order.
+type Person struct {
+	Email   string
+	SlackID string `mapstructure:"slack_id"`
+	Aliases []string
+}
+
+// ReadPeople reads a JSON or YAML file listing people:
+//
+//	people:
+//	  - email: alice@example.com
+//	    slack_id: U0123ABCD
+//	    aliases: [alice@contractor.example]
+func ReadPeople(path string) ([]Person, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read people file %s: %w", path, err)
+	}
+
+	var people []Person
+	if err := v.UnmarshalKey("people", &people); err != nil {
+		return nil, fmt.Errorf("failed to parse people file %s: %w", path, err)
+	}
+	return people, nil
+}
+
+// Slack is the part of the Slack client the Directory needs.
+type Slack interface {
+	GetUserByEmail(email string) (*slackAPI.User, error)
+	GetUserInfo(user string) (*slackAPI.User, error)
+}
+
+// Identity is what the Directory knows about an email or Slack user.
+type Identity struct {
+	Email   string         // the email written to the rota
+	Aliases []string       // other emails of the person
+	User    *slackAPI.User // nil when no Slack user was found
+	Via     string         // how the Slack user was found
+	Err     error          // why no Slack user was found
+	Expires time.Time      // when the Slack lookup is repeated
+}
+
+// Emails returns the email written to the rota followed by the aliases.
+func (i Identity) Emails() []string {
+	return append([]string{i.Email}, i.Aliases...)
+}
+
+// Directory resolves rota emails to Slack users and back. Slack lookups,
+// including users that weren't found, are cached for the TTL.
+type Directory struct {
+	slack   Slack
+	ttl     time.Duration
+	people  map[string]*Person // by lower case email and alias
+	bySlack map[string]*Person
+	now     func() time.Time
+
+	mu      sync.Mutex
+	byEmail map[string]Identity
+	byUser  map[string]Identity
+}
+
+// NewDirectory returns a Directory of the people looking up everyone else in Slack.
+func NewDirectory(slack Slack, ttl time.Duration, people []Person) (*Directory, error) {
+	d := &Directory{
+		slack:   slack,
+		ttl:     ttl,
+		people:  map[string]*Person{},
+		bySlack: map[string]*Person{},
+		now:     time.Now,
+		byEmail: map[string]Identity{},
+		byUser:  map[string]Identity{},
+	}
+
+	for i := range people {
+		p := &people[i]
+		if p.Email == "" {
+			return nil, fmt.Errorf("person %d has no email", i)
+		}
+		for _, email := range append([]string{p.Email}, p.Aliases...) {
+			key := strings.ToLower(email)
+			if d.people[key] != nil {
+				return nil, fmt.Errorf("email %s belongs to more than one person", email)
+			}
+			d.people[key] = p
+		}
+		if p.SlackID != "" {
+			d.bySlack[p.SlackID] = p
+		}
+	}
+
+	return d, nil
+}
+
+// Same reports whether two emails belong to the same person.
+func (d *Directory) Same(a, b string) bool {
+	return strings.EqualFold(d.canonical(a), d.canonical(b))
+}
+
+// canonical returns the email written to the rota for any email of a person.
+func (d *Directory) canonical(email string) string {
+	if p := d.people[strings.ToLower(email)]; p != nil {
+		return p.Email
+	}
+	return email
+}
+
+// User returns the Slack user of the person with the email.
+func (d *Directory) User(email string) (*slackAPI.User, error) {
+	id := d.Lookup(email)
+	if id.User == nil {
+		return nil, id.Err
+	}
+	return id.User, nil
+}
+
+// Lookup returns everything known about the person with the email.
+func (d *Directory) Lookup(email string) Identity {
+	key := strings.ToLower(email)
+
+	d.mu.Lock()
+	cached, ok := d.byEmail[key]
+	d.mu.Unlock()
+	if ok && d.now().Before(cached.Expires) {
+		return cached
+	}
+
+	id := Identity{Email: email}
+	if p := d.people[key]; p != nil {
+		id.Email = p.Email
+		id.Aliases = p.Aliases
+	}
+
+	var transient bool
+	id.User, id.Via, transient, id.Err = d.find(id)
+	id.Expires = d.now().Add(d.ttl)
+	if !transient {
+		d.mu.Lock()
+		d.byEmail[key] = id
+		d.mu.Unlock()
+	}
+
+	return id
+}
+
+// find looks the person up in Slack, by the configured Slack ID or else by
+// each of their emails. transient is set for errors other than not found.
+func (d *Directory) find(id Identity) (user *slackAPI.User, via string, transient bool, err error) {
+	if p := d.people[strings.ToLower(id.Email)]; p != nil && p.SlackID != "" {
+		user, err = d.slack.GetUserInfo(p.SlackID)
+		if err != nil {
+			return nil, "", !notFound(err), fmt.Errorf("failed to look up Slack user %s of %s: %w", p.SlackID, id.Email, err)
+		}
+		return user, "the configured Slack ID", false, nil
+	}
+
+	for _, email := range id.Emails() {
+		user, err = d.slack.GetUserByEmail(email)
+		if err == nil {
+			return user, fmt.Sprintf("the Slack email %s", email), false, nil
+		}
+		if !notFound(err) {
+			return nil, "", true, fmt.Errorf("failed to look up %s in Slack: %w", email, err)
+		}
+	}
+	return nil, "", false, fmt.Errorf("no Slack user has the email %s", strings.Join(id.Emails(), " or "))
+}
+
+// ForUser returns the person behind a Slack user ID.
+func (d *Directory) ForUser(userID string) (Identity, error) {
+	d.mu.Lock()
+	cached, ok := d.byUser[userID]
+	d.mu.Unlock()
+	if ok && d.now().Before(cached.Expires) {
+		return cached, nil
+	}
+
+	user, err := d.slack.GetUserInfo(userID)
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to look up user %s: %w", userID, err)
+	}
+
+	id := Identity{Email: user.Profile.Email, User: user, Via: "their Slack profile", Expires: d.now().Add(d.ttl)}
+	if p := d.bySlack[userID]; p != nil {
+		id.Email, id.Aliases, id.Via = p.Email, p.Aliases, "the configured Slack ID"
+	} else if p := d.people[strings.ToLower(user.Profile.Email)]; p != nil {
+		id.Email, id.Aliases = p.Email, p.Aliases
+	}
+
+	d.mu.Lock()
+	d.byUser[userID] = id
+	d.mu.Unlock()
+
+	return id, nil
+}
+
+// notFound reports whether Slack didn't know the user, rather than failed.
+func notFound(err error) bool {
+	msg := err.Error()
+	return msg == "users_not_found" || msg == "user_not_found"
+}
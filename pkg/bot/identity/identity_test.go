@@ -0,0 +1,201 @@
+package identity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	slackAPI "github.com/slack-go/slack"
+)
+
+// fakeSlack knows users by email and ID and counts the lookups
+type fakeSlack struct {
+	users   []*slackAPI.User
+	down    bool // fail every lookup as if Slack was unreachable
+	lookups int
+}
+
+func (f *fakeSlack) GetUserByEmail(email string) (*slackAPI.User, error) {
+	f.lookups++
+	if f.down {
+		return nil, errors.New("slack is down")
+	}
+	for _, u := range f.users {
+		if u.Profile.Email == email {
+			return u, nil
+		}
+	}
+	return nil, errors.New("users_not_found")
+}
+
+func (f *fakeSlack) GetUserInfo(user string) (*slackAPI.User, error) {
+	f.lookups++
+	if f.down {
+		return nil, errors.New("slack is down")
+	}
+	for _, u := range f.users {
+		if u.ID == user {
+			return u, nil
+		}
+	}
+	return nil, errors.New("user_not_found")
+}
+
+func slackUser(id, email string) *slackAPI.User {
+	return &slackAPI.User{ID: id, Name: id, Profile: slackAPI.UserProfile{Email: email}}
+}
+
+var testPeople = []Person{
+	{Email: "alice@example.com", Aliases: []string{"alice@contractor.example"}},
+	{Email: "bob@example.com", SlackID: "UBOB"},
+}
+
+func TestLookup(t *testing.T) {
+	slack := &fakeSlack{users: []*slackAPI.User{
+		slackUser("UALICE", "alice@contractor.example"),
+		slackUser("UBOB", "robert@example.com"),
+		slackUser("UCAROL", "carol@example.com"),
+	}}
+	d, err := NewDirectory(slack, time.Hour, testPeople)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		email     string
+		wantEmail string
+		wantUser  string
+	}{
+		{"alias found in Slack", "alice@example.com", "alice@example.com", "UALICE"},
+		{"looked up by alias", "Alice@Contractor.example", "alice@example.com", "UALICE"},
+		{"configured Slack ID", "bob@example.com", "bob@example.com", "UBOB"},
+		{"not configured", "carol@example.com", "carol@example.com", "UCAROL"},
+		{"unknown", "dave@example.com", "dave@example.com", ""},
+	}
+	for _, tt := range tests {
+		id := d.Lookup(tt.email)
+		if id.Email != tt.wantEmail {
+			t.Errorf("%s: Email = %q, want %q", tt.name, id.Email, tt.wantEmail)
+		}
+		var user string
+		if id.User != nil {
+			user = id.User.ID
+		}
+		if user != tt.wantUser {
+			t.Errorf("%s: User = %q, want %q", tt.name, user, tt.wantUser)
+		}
+		if tt.wantUser == "" && id.Err == nil {
+			t.Errorf("%s: Err is nil for a missing user", tt.name)
+		}
+	}
+}
+
+func TestSame(t *testing.T) {
+	d, err := NewDirectory(&fakeSlack{}, time.Hour, testPeople)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"alice@example.com", "alice@example.com", true},
+		{"alice@example.com", "ALICE@example.com", true},
+		{"alice@example.com", "alice@contractor.example", true},
+		{"alice@contractor.example", "bob@example.com", false},
+		{"carol@example.com", "Carol@Example.com", true},
+		{"carol@example.com", "dave@example.com", false},
+	}
+	for _, tt := range tests {
+		if got := d.Same(tt.a, tt.b); got != tt.want {
+			t.Errorf("Same(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLookupCache(t *testing.T) {
+	clock := time.Date(2020, time.June, 15, 9, 0, 0, 0, time.UTC)
+	slack := &fakeSlack{users: []*slackAPI.User{slackUser("UCAROL", "carol@example.com")}}
+	d, err := NewDirectory(slack, time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.now = func() time.Time { return clock }
+
+	tests := []struct {
+		name        string
+		after       time.Duration
+		email       string
+		down        bool
+		wantLookups int
+	}{
+		{"first lookup", 0, "carol@example.com", false, 1},
+		{"cached", 30 * time.Minute, "carol@example.com", false, 1},
+		{"expired", 31 * time.Minute, "carol@example.com", false, 2},
+		{"missing user", 0, "dave@example.com", false, 3},
+		{"missing user cached", 10 * time.Minute, "dave@example.com", false, 3},
+		{"failed lookup", 0, "erin@example.com", true, 4},
+		{"failed lookup not cached", 0, "erin@example.com", true, 5},
+	}
+	for _, tt := range tests {
+		clock = clock.Add(tt.after)
+		slack.down = tt.down
+		d.Lookup(tt.email)
+		if slack.lookups != tt.wantLookups {
+			t.Errorf("%s: %d Slack lookups, want %d", tt.name, slack.lookups, tt.wantLookups)
+		}
+	}
+}
+
+func TestForUser(t *testing.T) {
+	slack := &fakeSlack{users: []*slackAPI.User{
+		slackUser("UALICE", "alice@contractor.example"),
+		slackUser("UBOB", "robert@example.com"),
+	}}
+	d, err := NewDirectory(slack, time.Hour, testPeople)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		user string
+		want string
+	}{
+		{"UALICE", "alice@example.com"},
+		{"UBOB", "bob@example.com"},
+	}
+	for _, tt := range tests {
+		id, err := d.ForUser(tt.user)
+		if err != nil {
+			t.Errorf("ForUser(%s) failed: %v", tt.user, err)
+			continue
+		}
+		if id.Email != tt.want {
+			t.Errorf("ForUser(%s) = %q, want %q", tt.user, id.Email, tt.want)
+		}
+	}
+
+	if _, err := d.ForUser("UNOBODY"); err == nil {
+		t.Error("ForUser of an unknown user didn't fail")
+	}
+}
+
+func TestNewDirectoryErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		people []Person
+	}{
+		{"no email", []Person{{SlackID: "UALICE"}}},
+		{"shared alias", []Person{
+			{Email: "alice@example.com", Aliases: []string{"oncall@example.com"}},
+			{Email: "bob@example.com", Aliases: []string{"OnCall@example.com"}},
+		}},
+	}
+	for _, tt := range tests {
+		if _, err := NewDirectory(&fakeSlack{}, time.Hour, tt.people); err == nil {
+			t.Errorf("%s: NewDirectory didn't fail", tt.name)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	httpserver "github.com/dombo/hiberBot/pkg/bot/custom-http-server"
+	slackAPI "github.com/slack-go/slack"
+)
+
+// InteractionsRouter handles the payloads Slack sends when someone clicks a
+// button of one of our Block Kit messages. The Slack app's interactivity
+// request URL must point at the /slack/interactions path of the HTTP server.
+func (b *Bot) InteractionsRouter(evt httpserver.RequestEvent) error {
+	form, err := url.ParseQuery(string(evt.Body))
+	if err != nil {
+		return fmt.Errorf("failed to parse interaction body %v", err)
+	}
+
+	var callback slackAPI.InteractionCallback
+	if err := json.Unmarshal([]byte(form.Get("payload")), &callback); err != nil {
+		return fmt.Errorf("failed to parse interaction payload %v", err)
+	}
+
+	if callback.Token != b.conf.Slack.VerificationToken {
+		b.Logger.Error("Received an interaction with an invalid verification token")
+		return nil
+	}
+
+	for _, action := range callback.ActionCallback.BlockActions {
+		var err error
+		switch action.ActionID {
+		case actionRotaChangeAccept, actionRotaChangeDecline:
+			err = b.RotaChangeResponse(callback, action)
+		case actionRotaGenerateApprove, actionRotaGenerateDiscard:
+			err = b.RotaGenerationResponse(callback, action)
+		case actionSignoffItem:
+			err = b.SignoffItemResponse(callback, action)
+		case actionIncidentPostmortem:
+			err = b.IncidentPostmortemResponse(callback, action)
+		default:
+			b.Logger.Info(fmt.Sprintf("ignoring unknown interaction %s", action.ActionID))
+		}
+		if err != nil {
+			b.Logger.Error(fmt.Sprintf("interaction %s error %v", action.ActionID, err))
+		}
+	}
+
+	return nil
+}
+
+// replaceInteractiveMessage swaps the message a button was clicked in for plain text
+func (b *Bot) replaceInteractiveMessage(callback slackAPI.InteractionCallback, text string) {
+	_, _, _, err := b.Slack.UpdateMessage(callback.Channel.ID, callback.Message.Timestamp,
+		slackAPI.MsgOptionText(text, false),
+		slackAPI.MsgOptionBlocks(markdownSection(text)),
+	)
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("error updating interactive message %v", err))
+	}
+}
+
+// markdownSection is a Block Kit section with markdown text
+func markdownSection(text string) *slackAPI.SectionBlock {
+	return slackAPI.NewSectionBlock(slackAPI.NewTextBlockObject(slackAPI.MarkdownType, text, false, false), nil, nil)
+}
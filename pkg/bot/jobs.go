@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"fmt"
+)
+
+const jobActionMessage = "message"
+
+// jobActions are what a configured job can do for its team
+var jobActions = map[string]func(b *Bot, job JobConfig, team TeamConfig){
+	"start_of_day":      func(b *Bot, _ JobConfig, team TeamConfig) { b.AtStartOfDay(StartOfDayEvent{Team: team.Name}) },
+	"before_end_of_day": func(b *Bot, _ JobConfig, team TeamConfig) { b.BeforeEndOfDay(BeforeEndOfDayEvent{Team: team.Name}) },
+	"end_of_day":        func(b *Bot, _ JobConfig, team TeamConfig) { b.AtEndOfDay(EndOfDayEvent{Team: team.Name}) },
+	"topic":             func(b *Bot, _ JobConfig, team TeamConfig) { b.DailySetQuestionsChannelTopic(team.Name) },
+	"runbook":           func(b *Bot, _ JobConfig, team TeamConfig) { b.DailySendLevel1TheRunbook(team.Name) },
+	"handover_digest":   func(b *Bot, _ JobConfig, team TeamConfig) { b.DailySendHandoverDigest(team.Name) },
+	"rota_check":        func(b *Bot, _ JobConfig, team TeamConfig) { b.DailyWarnAboutRotaProblems(team.Name) },
+	"rota_stats":        func(b *Bot, _ JobConfig, team TeamConfig) { b.MonthlyReportRotaStats(team.Name) },
+	"rota_absence":      func(b *Bot, _ JobConfig, team TeamConfig) { b.DailyWarnAboutAbsences(team.Name) },
+	"usergroup_sync":    func(b *Bot, _ JobConfig, team TeamConfig) { b.SyncUsergroups(team.Name, 0) },
+	jobActionMessage: func(b *Bot, job JobConfig, team TeamConfig) {
+		channel := job.Channel
+		if channel == "" {
+			channel = team.QuestionsChannel
+		}
+		if channel == "" {
+			b.Logger.Error(fmt.Sprintf("job %s has no channel to post to", job.Name))
+			return
+		}
+		b.notify(channel, job.Text)
+	},
+}
+
+// RunJob runs the configured job named by the event
+func (b *Bot) RunJob(evt JobEvent) {
+	for _, job := range b.conf.Jobs {
+		if job.Name != evt.Name {
+			continue
+		}
+
+		b.Logger.Info(fmt.Sprintf("running job %s", job.Name))
+		jobActions[job.Action](b, job, b.conf.JobTeam(job))
+		return
+	}
+	b.Logger.Error(fmt.Sprintf("unknown job %s", evt.Name))
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltBucket holds every key of the bot.
+var boltBucket = []byte("srebot")
+
+// Bolt keeps the memory in an embedded BoltDB database.
+type Bolt struct {
+	db *bolt.DB
+}
+
+// NewBolt opens or creates the database at path. Only one process can have
+// it open at a time.
+func NewBolt(path string) (*Bolt, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open memory database %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create memory bucket in %s: %w", path, err)
+	}
+
+	return &Bolt{db: db}, nil
+}
+
+func (b *Bolt) Set(key string, value []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), value)
+	})
+}
+
+func (b *Bolt) Get(key string) ([]byte, bool, error) {
+	var value []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(boltBucket).Get([]byte(key)); v != nil {
+			// v is only valid during the transaction
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+	return value, value != nil, err
+}
+
+func (b *Bolt) Delete(key string) (bool, error) {
+	var found bool
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		found = bucket.Get([]byte(key)) != nil
+		return bucket.Delete([]byte(key))
+	})
+	return found, err
+}
+
+func (b *Bolt) Keys() ([]string, error) {
+	var keys []string
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	return keys, err
+}
+
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// File keeps every key in memory and writes them all to a JSON file on each
+// change. It suits the small amount of state the bot keeps.
+type File struct {
+	path string
+
+	mu   sync.Mutex
+	data map[string]string
+}
+
+// NewFile reads the memory from the file at path, which is created on the
+// first change when it doesn't exist.
+func NewFile(path string) (*File, error) {
+	f := &File{path: path, data: map[string]string{}}
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory file %s: %w", path, err)
+	}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &f.data); err != nil {
+			return nil, fmt.Errorf("failed to parse memory file %s: %w", path, err)
+		}
+	}
+	return f, nil
+}
+
+func (f *File) Set(key string, value []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.data[key] = string(value)
+	return f.write()
+}
+
+func (f *File) Get(key string) ([]byte, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	value, ok := f.data[key]
+	return []byte(value), ok, nil
+}
+
+func (f *File) Delete(key string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.data[key]; !ok {
+		return false, nil
+	}
+	delete(f.data, key)
+	return true, f.write()
+}
+
+func (f *File) Keys() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.data))
+	for k := range f.data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (f *File) Close() error {
+	return nil
+}
+
+// write replaces the file through a temporary file so a crash never leaves
+// it half written.
+func (f *File) write() error {
+	content, err := json.MarshalIndent(f.data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write memory file %s: %w", f.path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write memory file %s: %w", f.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write memory file %s: %w", f.path, err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to write memory file %s: %w", f.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Package memory provides persistent joe.Memory backends so what the bot
+// remembers, such as handover notes and pending rota changes, survives a
+// restart.
+package memory
+
+import (
+	"fmt"
+
+	"github.com/go-joe/joe"
+)
+
+// Supported types of Open
+const (
+	TypeFile = "file"
+	TypeBolt = "bolt"
+)
+
+// Open returns the memory of the type, file or bolt, at path.
+func Open(kind, path string) (joe.Memory, error) {
+	switch kind {
+	case TypeFile:
+		return NewFile(path)
+	case TypeBolt:
+		return NewBolt(path)
+	default:
+		return nil, fmt.Errorf("unknown memory type %q", kind)
+	}
+}
+
+// Module is a joe.Module making the bot remember in the memory of the type at
+// path, after migrating it.
+func Module(kind, path string, migrations []Migration) joe.Module {
+	return joe.ModuleFunc(func(conf *joe.Config) error {
+		m, err := Open(kind, path)
+		if err != nil {
+			return err
+		}
+
+		from, to, err := Migrate(m, migrations)
+		if err != nil {
+			m.Close()
+			return err
+		}
+		if from != to {
+			conf.Logger("memory").Info(fmt.Sprintf("migrated memory %s from version %d to %d", path, from, to))
+		}
+
+		conf.SetMemory(m)
+		return nil
+	})
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-joe/joe"
+)
+
+var kinds = []string{TypeFile, TypeBolt}
+
+// tempPath returns a path in a temporary folder for a memory of the kind
+func tempPath(t *testing.T, kind string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "memory."+kind)
+}
+
+// open opens the memory of the kind at path, failing the test on errors
+func open(t *testing.T, kind, path string) joe.Memory {
+	t.Helper()
+	m, err := Open(kind, path)
+	if err != nil {
+		t.Fatalf("%s: %v", kind, err)
+	}
+	return m
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, kind := range kinds {
+		path := tempPath(t, kind)
+
+		m := open(t, kind, path)
+		for _, key := range []string{"handoff.sre", "rota.change.1", "incident.INC-1"} {
+			if err := m.Set(key, []byte(`"`+key+`"`)); err != nil {
+				t.Fatalf("%s: Set(%s) failed: %v", kind, key, err)
+			}
+		}
+		if found, err := m.Delete("rota.change.1"); err != nil || !found {
+			t.Errorf("%s: Delete() = %v, %v, want true", kind, found, err)
+		}
+		if found, err := m.Delete("rota.change.1"); err != nil || found {
+			t.Errorf("%s: Delete() of a deleted key = %v, %v, want false", kind, found, err)
+		}
+		if err := m.Close(); err != nil {
+			t.Fatalf("%s: Close() failed: %v", kind, err)
+		}
+
+		// What was remembered survives a restart
+		m = open(t, kind, path)
+		keys, err := m.Keys()
+		if err != nil {
+			t.Fatalf("%s: Keys() failed: %v", kind, err)
+		}
+		if want := []string{"handoff.sre", "incident.INC-1"}; !reflect.DeepEqual(keys, want) {
+			t.Errorf("%s: Keys() = %v, want %v", kind, keys, want)
+		}
+
+		value, ok, err := m.Get("handoff.sre")
+		if err != nil || !ok || string(value) != `"handoff.sre"` {
+			t.Errorf("%s: Get() = %q, %v, %v, want the stored value", kind, value, ok, err)
+		}
+		if _, ok, err := m.Get("rota.change.1"); err != nil || ok {
+			t.Errorf("%s: Get() of a deleted key = %v, %v, want not found", kind, ok, err)
+		}
+		m.Close()
+	}
+}
+
+func TestOpenUnknownType(t *testing.T) {
+	if _, err := Open("redis", tempPath(t, "redis")); err == nil {
+		t.Error("Open of an unknown type didn't fail")
+	}
+}
@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/go-joe/joe"
+)
+
+// VersionKey stores the schema version of the memory.
+const VersionKey = "memory.version"
+
+// Migration upgrades the stored keys from one schema version to the next.
+type Migration func(m joe.Memory) error
+
+// Migrate brings the memory up to date, migrations[i] upgrades version i to
+// i+1. A memory without a version is at version 0. The version is stored
+// after every migration so a failure resumes where it stopped.
+func Migrate(m joe.Memory, migrations []Migration) (from, to int, err error) {
+	raw, ok, err := m.Get(VersionKey)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read memory version: %w", err)
+	}
+	if ok {
+		from, err = strconv.Atoi(string(raw))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid memory version %q", raw)
+		}
+	}
+	if from > len(migrations) {
+		return from, from, fmt.Errorf("memory version %d is newer than this bot, which knows version %d", from, len(migrations))
+	}
+
+	for to = from; to < len(migrations); to++ {
+		if err := migrations[to](m); err != nil {
+			return from, to, fmt.Errorf("failed to migrate memory to version %d: %w", to+1, err)
+		}
+		// A plain number is valid JSON for the store's encoder as well
+		if err := m.Set(VersionKey, []byte(strconv.Itoa(to+1))); err != nil {
+			return from, to, fmt.Errorf("failed to write memory version: %w", err)
+		}
+	}
+
+	return from, to, nil
+}
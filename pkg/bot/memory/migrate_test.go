@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-joe/joe"
+)
+
+// rename returns a migration moving the value of one key to another
+func rename(from, to string) Migration {
+	return func(m joe.Memory) error {
+		value, ok, err := m.Get(from)
+		if err != nil || !ok {
+			return err
+		}
+		if err := m.Set(to, value); err != nil {
+			return err
+		}
+		_, err = m.Delete(from)
+		return err
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	for _, kind := range kinds {
+		path := tempPath(t, kind)
+
+		m := open(t, kind, path)
+		if err := m.Set("handover", []byte(`"notes"`)); err != nil {
+			t.Fatal(err)
+		}
+		migrations := []Migration{rename("handover", "handoff.sre")}
+		if from, to, err := Migrate(m, migrations); err != nil || from != 0 || to != 1 {
+			t.Errorf("%s: Migrate() = %d, %d, %v, want 0, 1", kind, from, to, err)
+		}
+		m.Close()
+
+		// After a restart only the new migrations run
+		m = open(t, kind, path)
+		var ran int
+		migrations = append(migrations, func(joe.Memory) error { ran++; return nil })
+		if from, to, err := Migrate(m, migrations); err != nil || from != 1 || to != 2 {
+			t.Errorf("%s: Migrate() after a restart = %d, %d, %v, want 1, 2", kind, from, to, err)
+		}
+		if ran != 1 {
+			t.Errorf("%s: the new migration ran %d times, want once", kind, ran)
+		}
+
+		keys, err := m.Keys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 2 || keys[0] != "handoff.sre" || keys[1] != VersionKey {
+			t.Errorf("%s: Keys() after migrating = %v, want handoff.sre and %s", kind, keys, VersionKey)
+		}
+
+		// A failed migration resumes there next time
+		failing := append(migrations, func(joe.Memory) error { return errors.New("broken") })
+		if _, to, err := Migrate(m, failing); err == nil || to != 2 {
+			t.Errorf("%s: Migrate() with a failing migration = %d, %v, want an error at 2", kind, to, err)
+		}
+		if _, _, err := Migrate(m, migrations[:1]); err == nil {
+			t.Errorf("%s: Migrate() by an older bot didn't fail", kind)
+		}
+		m.Close()
+	}
+}
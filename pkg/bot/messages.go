@@ -0,0 +1,293 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-joe/joe"
+	slackAPI "github.com/slack-go/slack"
+)
+
+// Names of the daily messages, used by the preview command and the messages config
+const (
+	MessageRunbook         = "runbook"
+	MessageSignoff         = "signoff"
+	MessageCongratulations = "congratulations"
+)
+
+// messageData is what the message templates can use
+type messageData struct {
+	Team       string
+	Date       time.Time // now in the team's time zone
+	Holiday    string    // name of today's public holiday, empty on other days
+	Tier       string    // name of the first tier
+	Window     string    // when the first tier's shift runs, e.g. "today"
+	OnCall     messagePerson
+	Escalation []messagePerson // who is on the other tiers
+	Incidents  []messageIncident
+	Runbooks   []LinkConfig
+	Handover   string // digest of the last shift once it has finished
+}
+
+type messagePerson struct {
+	Tier string
+	ID   string // Slack user ID, empty when nobody is on call
+	Name string
+}
+
+type messageIncident struct {
+	Title   string
+	Channel string // Slack channel ID of the incident
+	Since   time.Time
+}
+
+// renderedMessage is the JSON a message template renders
+type renderedMessage struct {
+	Text   string          // fallback shown in notifications
+	Blocks slackAPI.Blocks // Block Kit layout of the message
+}
+
+// messageFuncs are the helpers available to the message templates
+var messageFuncs = template.FuncMap{
+	// json quotes a value for use in the Block Kit JSON, e.g. "text": {{json .Team}}
+	"json": func(v interface{}) (string, error) {
+		out, err := json.Marshal(v)
+		return string(out), err
+	},
+	"mention": func(p messagePerson) string {
+		if p.ID == "" {
+			return "nobody"
+		}
+		return fmt.Sprintf("<@%s>", p.ID)
+	},
+	"channel": func(id string) string {
+		return fmt.Sprintf("<#%s>", id)
+	},
+	"link": func(l LinkConfig) string {
+		return fmt.Sprintf("<%s|%s>", l.Url, l.Title)
+	},
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	// truncate keeps texts under the Block Kit limits, sections take 3000 characters
+	"truncate": func(n int, s string) string {
+		if r := []rune(s); len(r) > n {
+			return string(r[:n-1]) + "…"
+		}
+		return s
+	},
+}
+
+// messageTemplate parses the named message's template file, or its built-in
+// template when none is configured. Files are read every time so edits show
+// up without a restart.
+func (b *Bot) messageTemplate(name string) (*template.Template, error) {
+	text, ok := defaultMessageTemplates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown message %q, try %s", name, strings.Join(messageNames(), ", "))
+	}
+
+	if path := b.conf.Messages.File(name); path != "" {
+		return readMessageTemplate(name, path)
+	}
+	return template.New(name).Funcs(messageFuncs).Parse(text)
+}
+
+// readMessageTemplate parses the template file of the named message
+func readMessageTemplate(name, path string) (*template.Template, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the %s template %v", name, err)
+	}
+
+	tmpl, err := template.New(name).Funcs(messageFuncs).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the %s template %v", name, err)
+	}
+	return tmpl, nil
+}
+
+// renderMessage renders the named message into the options posting it
+func (b *Bot) renderMessage(name string, data messageData) ([]slackAPI.MsgOption, error) {
+	tmpl, err := b.messageTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, fmt.Errorf("failed to render the %s template %v", name, err)
+	}
+
+	var msg renderedMessage
+	if err := json.Unmarshal(out.Bytes(), &msg); err != nil {
+		return nil, fmt.Errorf("the %s template doesn't render Block Kit JSON %v", name, err)
+	}
+	if msg.Text == "" && len(msg.Blocks.BlockSet) == 0 {
+		return nil, fmt.Errorf("the %s template rendered an empty message", name)
+	}
+
+	return []slackAPI.MsgOption{
+		slackAPI.MsgOptionText(msg.Text, false),
+		slackAPI.MsgOptionBlocks(msg.Blocks.BlockSet...),
+	}, nil
+}
+
+// messageData collects what the team's daily messages show right now
+func (b *Bot) messageData(team string) (messageData, error) {
+	teamConf, _ := b.conf.Team(team)
+	at := time.Now().In(teamConf.Location())
+
+	rotas, err := b.getRota(team, at)
+	if err != nil {
+		return messageData{}, err
+	}
+
+	people := make([]messagePerson, 0, len(rotas))
+	for _, r := range rotas {
+		p := messagePerson{Tier: r.Tier.Name, Name: r.Name()}
+		if r.User != nil {
+			p.ID = r.User.ID
+		}
+		people = append(people, p)
+	}
+
+	data := messageData{
+		Team:       team,
+		Date:       at,
+		Tier:       rotas[0].Tier.Name,
+		Window:     rotas[0].Tier.Window.Describe(),
+		OnCall:     people[0],
+		Escalation: people[1:],
+		Runbooks:   teamConf.Runbooks,
+	}
+	if holiday := b.publicHoliday(team, at); holiday != nil {
+		data.Holiday = holiday.Name
+	}
+	for _, inc := range b.openIncidents(team) {
+		data.Incidents = append(data.Incidents, messageIncident{Title: inc.name(), Channel: inc.Channel, Since: inc.Declared})
+	}
+	if h, err := b.loadHandover(team); err != nil {
+		b.Logger.Error(err.Error())
+	} else if !h.To.IsZero() {
+		data.Handover = b.formatHandover(h, time.Now())
+	}
+	return data, nil
+}
+
+// sendMessage renders the named message of the team and posts it to the channel or user
+func (b *Bot) sendMessage(name, team, channelID string) {
+	data, err := b.messageData(team)
+	if err != nil {
+		b.Logger.Error(err.Error())
+		return
+	}
+
+	opts, err := b.renderMessage(name, data)
+	if err != nil {
+		b.Logger.Error(err.Error())
+		return
+	}
+
+	channelID, timestamp, err := b.Slack.PostMessage(channelID, opts...)
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("error sending the %s message %v", name, err))
+		return
+	}
+	b.Logger.Info(fmt.Sprintf("Message successfully sent to channel %s at %s", channelID, timestamp))
+}
+
+// Preview answers "preview <message> [team]" by posting the message as it would be sent now
+func (b *Bot) Preview(message joe.Message) error {
+	fields := strings.Fields(strings.TrimSpace(message.Text[len("preview"):]))
+	if len(fields) == 0 {
+		message.Respond("Try @%s preview <%s> [team]", b.Bot.Name, strings.Join(messageNames(), "|"))
+		return nil
+	}
+
+	team := b.conf.TeamForChannel(message.Channel)
+	if len(fields) > 1 {
+		t, ok := b.conf.Team(fields[1])
+		if !ok {
+			message.Respond("I don't know the team %s, try one of %s", fields[1], strings.Join(b.teamNames(), ", "))
+			return nil
+		}
+		team = t
+	}
+
+	data, err := b.messageData(team.Name)
+	if err != nil {
+		return err
+	}
+
+	opts, err := b.renderMessage(strings.ToLower(fields[0]), data)
+	if err != nil {
+		message.Respond("%v", err)
+		return nil
+	}
+
+	if _, _, err := b.Slack.PostMessage(message.Channel, opts...); err != nil {
+		message.Respond("Slack rejected the %s message: %v", fields[0], err)
+	}
+	return nil
+}
+
+func messageNames() []string {
+	return []string{MessageRunbook, MessageSignoff, MessageCongratulations}
+}
+
+// defaultMessageTemplates are used for messages without a template file, they
+// are a starting point for writing one
+var defaultMessageTemplates = map[string]string{
+	MessageRunbook: `{
+  "text": {{json (printf "You're on %s %s" .Tier .Window)}},
+  "blocks": [
+    {"type": "section", "text": {"type": "mrkdwn", "text": {{json (printf "Good morning! You're on *%s* for %s %s." .Tier .Team .Window)}}}}
+    {{- if .Holiday}},
+    {"type": "context", "elements": [{"type": "mrkdwn", "text": {{json (printf ":palm_tree: Today is %s, a public holiday." .Holiday)}}}]}
+    {{- end}}
+    {{- if .Escalation}},
+    {"type": "section", "text": {"type": "mrkdwn", "text": "Here's who you can escalate to:"}, "fields": [
+      {{- range $i, $p := .Escalation}}{{if $i}},{{end}}
+      {"type": "mrkdwn", "text": {{json (printf "*%s*\n%s" $p.Tier (mention $p))}}}
+      {{- end}}
+    ]}
+    {{- end}}
+    {{- range .Incidents}},
+    {"type": "section", "text": {"type": "mrkdwn", "text": {{json (printf ":rotating_light: %s in %s since %s" .Title (channel .Channel) (date "Mon 15:04" .Since))}}}}
+    {{- end}}
+    {{- if .Runbooks}},
+    {"type": "context", "elements": [
+      {{- range $i, $l := .Runbooks}}{{if $i}},{{end}}
+      {"type": "mrkdwn", "text": {{json (printf ":book: %s" (link $l))}}}
+      {{- end}}
+    ]}
+    {{- end}}
+    {{- if .Handover}},
+    {"type": "divider"},
+    {"type": "section", "text": {"type": "mrkdwn", "text": {{json (truncate 3000 .Handover)}}}}
+    {{- end}}
+  ]
+}`,
+	MessageSignoff: `{
+  "text": {{json (printf "Your %s shift for %s ends soon" .Tier .Team)}},
+  "blocks": [
+    {"type": "section", "text": {"type": "mrkdwn", "text": {{json (printf "Your *%s* shift for %s ends soon. Before you sign off, check nothing is left unanswered and write down what the next person should know." .Tier .Team)}}}}
+    {{- range .Incidents}},
+    {"type": "section", "text": {"type": "mrkdwn", "text": {{json (printf ":rotating_light: %s is still open in %s" .Title (channel .Channel))}}}}
+    {{- end}}
+  ]
+}`,
+	MessageCongratulations: `{
+  "text": {{json (printf "Thanks for covering %s today" .Tier)}},
+  "blocks": [
+    {"type": "section", "text": {"type": "mrkdwn", "text": {{json (printf "Thanks for covering *%s* for %s today :tada:" .Tier .Team)}}}},
+    {"type": "section", "text": {"type": "mrkdwn", "text": "Is there anything the next person should know? Reply with ` + "`handoff note <notes>`" + `, I'll pass it on at the start of the next shift."}}
+  ]
+}`,
+}
@@ -0,0 +1,25 @@
+package bot
+
+import (
+	"github.com/dombo/hiberBot/pkg/bot/memory"
+	"github.com/go-joe/joe"
+)
+
+// memoryMigrations upgrade what a persistent memory holds when the keys or
+// the shape of stored values change, memoryMigrations[i] upgrades version i
+// to i+1. Append to the list, never change a migration that was released.
+//
+// The keys in use are:
+//
+//	rota.change.<id>                   pending swap or cover, see rotaChange
+//	rota.generation.<id>               generated rota awaiting approval, see rotaGeneration
+//	rota.absence.<team>.<tier>.<email>.<start>  absence conflict already flagged
+//	handover.<team>                    handover being collected, see handover
+//	signoff.<team>.<date>              sign-off checklist of a day, kept for audits
+//	postmortem.<id>                    status and transitions of a postmortem, see postmortemRecord
+//	incident.<id>                      declared incident, see incident
+//	incidents.next                     number of the next incident
+var memoryMigrations = []memory.Migration{
+	// 1: the state as it was when memory became persistent, nothing to change
+	func(joe.Memory) error { return nil },
+}
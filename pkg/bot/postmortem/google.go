@@ -0,0 +1,271 @@
+package postmortem
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/drive/v3"
+)
+
+// propertyMarker is the Drive file property marking the documents created as
+// postmortems, the fields are kept as properties prefixed with propertyField
+const (
+	propertyMarker = "srebot_postmortem"
+	propertyField  = "pm_"
+)
+
+// maxPropertySize is the most bytes Drive allows for the key and value of a
+// file property together
+const maxPropertySize = 124
+
+// GoogleDocs copies a Google Docs template for every postmortem. The
+// placeholders of the fields become named ranges of the copy, so later
+// updates replace only the text of the field. The fields are also kept as
+// properties of the Drive file to list postmortems, shortened to fit.
+type GoogleDocs struct {
+	docs  *docs.Service
+	drive *drive.Service
+}
+
+// NewGoogleDocs returns a store writing postmortems to Google Docs.
+func NewGoogleDocs(docsSrv *docs.Service, driveSrv *drive.Service) *GoogleDocs {
+	return &GoogleDocs{docs: docsSrv, drive: driveSrv}
+}
+
+// Create copies the template document with the ID and fills in the fields.
+func (g *GoogleDocs) Create(template string, fields map[string]string) (Postmortem, error) {
+	files := drive.NewFilesService(g.drive)
+
+	tmpl, err := files.Get(template).Do()
+	if err != nil {
+		return Postmortem{}, fmt.Errorf("failed to get postmortem template %v", err)
+	}
+
+	name := fmt.Sprintf("%s.%s.Postmortem", time.Now().Format("2006-01-02"), strings.Replace(fields[FieldTitle], " ", "-", -1))
+	properties := map[string]string{propertyMarker: "true"}
+	for k, v := range fields {
+		properties[propertyField+k] = propertyValue(propertyField+k, v)
+	}
+
+	doc, err := files.Copy(tmpl.Id, &drive.File{Name: name, Properties: properties}).Do()
+	if err != nil {
+		return Postmortem{}, fmt.Errorf("failed to create file %v", err)
+	}
+
+	if err := g.setFields(doc.Id, fields); err != nil {
+		return Postmortem{}, err
+	}
+
+	return Postmortem{
+		ID:      doc.Id,
+		Title:   fields[FieldTitle],
+		Created: time.Now(),
+		URL:     documentURL(doc.Id),
+		Fields:  fields,
+	}, nil
+}
+
+// Update replaces the text of the fields in the document, or their
+// placeholders when the template didn't use them before.
+func (g *GoogleDocs) Update(id string, fields map[string]string) error {
+	if err := g.setFields(id, fields); err != nil {
+		return err
+	}
+
+	properties := map[string]string{}
+	for k, v := range fields {
+		properties[propertyField+k] = propertyValue(propertyField+k, v)
+	}
+	if _, err := drive.NewFilesService(g.drive).Update(id, &drive.File{Properties: properties}).Do(); err != nil {
+		return fmt.Errorf("failed to save the fields of postmortem %s %v", id, err)
+	}
+	return nil
+}
+
+// URL returns the link to edit the document.
+func (g *GoogleDocs) URL(id string) (string, error) {
+	return documentURL(id), nil
+}
+
+// List returns the documents created as postmortems that aren't in the trash.
+func (g *GoogleDocs) List() ([]Postmortem, error) {
+	files := drive.NewFilesService(g.drive)
+	call := files.List().
+		Q(fmt.Sprintf("properties has { key='%s' and value='true' } and trashed = false", propertyMarker)).
+		OrderBy("createdTime desc").
+		Fields("nextPageToken", "files(id, name, createdTime, properties)")
+
+	var postmortems []Postmortem
+	for {
+		list, err := call.Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list postmortems %v", err)
+		}
+
+		for _, f := range list.Files {
+			p := Postmortem{ID: f.Id, Title: f.Name, URL: documentURL(f.Id), Fields: map[string]string{}}
+			for k, v := range f.Properties {
+				if strings.HasPrefix(k, propertyField) {
+					p.Fields[strings.TrimPrefix(k, propertyField)] = v
+				}
+			}
+			if title := p.Fields[FieldTitle]; title != "" {
+				p.Title = title
+			}
+			p.Created, _ = time.Parse(time.RFC3339, f.CreatedTime)
+			postmortems = append(postmortems, p)
+		}
+
+		if list.NextPageToken == "" {
+			break
+		}
+		call = call.PageToken(list.NextPageToken)
+	}
+
+	sort.SliceStable(postmortems, func(i, j int) bool { return postmortems[i].Created.After(postmortems[j].Created) })
+	return postmortems, nil
+}
+
+// setFields turns the placeholders of the fields into named ranges and
+// replaces the content of the ranges, so only the text of a field changes
+// when it is updated. Placeholders of empty fields are kept.
+func (g *GoogleDocs) setFields(id string, fields map[string]string) error {
+	doc, err := docs.NewDocumentsService(g.docs).Get(id).Do()
+	if err != nil {
+		return fmt.Errorf("failed to get postmortem %s %v", id, err)
+	}
+
+	lower := map[string]string{}
+	for k, v := range fields {
+		if v != "" {
+			lower[strings.ToLower(k)] = k
+		}
+	}
+
+	var requests []*docs.Request
+	found := map[string]bool{}
+	add := func(segment string, content []*docs.StructuralElement) {
+		for _, p := range placeholderRanges(content) {
+			k, ok := lower[strings.ToLower(p.field)]
+			if !ok {
+				continue
+			}
+			p.Range.SegmentId = segment
+			requests = append(requests, &docs.Request{
+				CreateNamedRange: &docs.CreateNamedRangeRequest{Name: propertyField + k, Range: p.Range},
+			})
+			found[k] = true
+		}
+	}
+	if doc.Body != nil {
+		add("", doc.Body.Content)
+	}
+	for segment, h := range doc.Headers {
+		add(segment, h.Content)
+	}
+	for segment, f := range doc.Footers {
+		add(segment, f.Content)
+	}
+
+	// The requests are applied in order, so the ranges are named before their
+	// content is replaced
+	for _, k := range lower {
+		if _, ok := doc.NamedRanges[propertyField+k]; !ok && !found[k] {
+			continue
+		}
+		requests = append(requests, &docs.Request{
+			ReplaceNamedRangeContent: &docs.ReplaceNamedRangeContentRequest{NamedRangeName: propertyField + k, Text: fields[k]},
+		})
+	}
+	return g.batchUpdate(id, requests)
+}
+
+func (g *GoogleDocs) batchUpdate(id string, requests []*docs.Request) error {
+	if len(requests) == 0 {
+		return nil
+	}
+
+	res, err := docs.NewDocumentsService(g.docs).BatchUpdate(id, &docs.BatchUpdateDocumentRequest{Requests: requests}).Do()
+	if err != nil {
+		return fmt.Errorf("failed to update the postmortem text %v", err)
+	}
+	if res.HTTPStatusCode != 200 {
+		return fmt.Errorf("failed to update the postmortem text, status %d", res.HTTPStatusCode)
+	}
+	return nil
+}
+
+// propertyValue shortens the value of a field so its property fits into
+// Drive's limit, the document itself keeps the whole value
+func propertyValue(key, value string) string {
+	max := maxPropertySize - len(key)
+	if len(value) <= max {
+		return value
+	}
+
+	cut := max - len("…")
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut] + "…"
+}
+
+// fieldRange is where a placeholder of the field is in the document
+type fieldRange struct {
+	field string
+	*docs.Range
+}
+
+// placeholderRanges finds the placeholders in the paragraphs and tables of
+// the content. A placeholder can span text runs with different styles, and
+// the indexes are in UTF-16 code units like everywhere in the Docs API.
+func placeholderRanges(content []*docs.StructuralElement) []fieldRange {
+	var ranges []fieldRange
+	for _, e := range content {
+		if e.Table != nil {
+			for _, row := range e.Table.TableRows {
+				for _, cell := range row.TableCells {
+					ranges = append(ranges, placeholderRanges(cell.Content)...)
+				}
+			}
+		}
+		if e.Paragraph == nil {
+			continue
+		}
+
+		var text strings.Builder
+		var index []int64 // the document index of every byte of text
+		for _, pe := range e.Paragraph.Elements {
+			if pe.TextRun == nil {
+				continue
+			}
+			at := pe.StartIndex
+			for _, r := range pe.TextRun.Content {
+				n, _ := text.WriteRune(r)
+				for i := 0; i < n; i++ {
+					index = append(index, at)
+				}
+				at += int64(len(utf16.Encode([]rune{r})))
+			}
+		}
+
+		s := text.String()
+		for _, m := range placeholder.FindAllStringSubmatchIndex(s, -1) {
+			ranges = append(ranges, fieldRange{
+				field: s[m[2]:m[3]],
+				// the placeholder ends with a brace, a single code unit
+				Range: &docs.Range{StartIndex: index[m[0]], EndIndex: index[m[1]-1] + 1},
+			})
+		}
+	}
+	return ranges
+}
+
+func documentURL(id string) string {
+	return fmt.Sprintf("https://docs.google.com/document/d/%s/edit#", id)
+}
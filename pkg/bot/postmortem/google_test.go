@@ -0,0 +1,97 @@
+package postmortem
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"google.golang.org/api/docs/v1"
+)
+
+func TestPropertyValue(t *testing.T) {
+	key := propertyField + FieldTitle
+	long := strings.Repeat("a", maxPropertySize)
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"short", "Service outage", "Service outage"},
+		{"exact", long[:maxPropertySize-len(key)], long[:maxPropertySize-len(key)]},
+		{"long", long, long[:maxPropertySize-len(key)-len("…")] + "…"},
+		{"multibyte", strings.Repeat("é", maxPropertySize), strings.Repeat("é", (maxPropertySize-len(key)-len("…"))/2) + "…"},
+	}
+	for _, tt := range tests {
+		got := propertyValue(key, tt.value)
+		if got != tt.want {
+			t.Errorf("%s: propertyValue() = %q, want %q", tt.name, got, tt.want)
+		}
+		if len(key)+len(got) > maxPropertySize || !utf8.ValidString(got) {
+			t.Errorf("%s: propertyValue() = %q doesn't fit a property", tt.name, got)
+		}
+	}
+}
+
+// paragraph returns a paragraph of text runs starting at the index
+func paragraph(at int64, runs ...string) *docs.StructuralElement {
+	p := &docs.Paragraph{}
+	for _, run := range runs {
+		p.Elements = append(p.Elements, &docs.ParagraphElement{StartIndex: at, TextRun: &docs.TextRun{Content: run}})
+		at += int64(len(utf16.Encode([]rune(run))))
+	}
+	return &docs.StructuralElement{Paragraph: p}
+}
+
+func TestPlaceholderRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []*docs.StructuralElement
+		want    []fieldRange
+	}{
+		{
+			"plain",
+			[]*docs.StructuralElement{paragraph(1, "Status: {{status}}\n")},
+			[]fieldRange{{"status", &docs.Range{StartIndex: 9, EndIndex: 19}}},
+		},
+		{
+			"spaces and several",
+			[]*docs.StructuralElement{paragraph(5, "{{ title }} by {{team}}\n")},
+			[]fieldRange{
+				{"title", &docs.Range{StartIndex: 5, EndIndex: 16}},
+				{"team", &docs.Range{StartIndex: 20, EndIndex: 28}},
+			},
+		},
+		{
+			"styled runs",
+			[]*docs.StructuralElement{paragraph(1, "Owner: {{", "reviewer", "}}\n")},
+			[]fieldRange{{"reviewer", &docs.Range{StartIndex: 8, EndIndex: 20}}},
+		},
+		{
+			"surrogate pairs",
+			[]*docs.StructuralElement{paragraph(1, "🔥 é {{due}}\n")},
+			[]fieldRange{{"due", &docs.Range{StartIndex: 6, EndIndex: 13}}},
+		},
+		{
+			"table",
+			[]*docs.StructuralElement{{Table: &docs.Table{TableRows: []*docs.TableRow{
+				{TableCells: []*docs.TableCell{
+					{Content: []*docs.StructuralElement{paragraph(4, "Date\n")}},
+					{Content: []*docs.StructuralElement{paragraph(10, "{{date}}\n")}},
+				}},
+			}}}},
+			[]fieldRange{{"date", &docs.Range{StartIndex: 10, EndIndex: 18}}},
+		},
+		{
+			"none",
+			[]*docs.StructuralElement{paragraph(1, "{{ not closed\n"), {}},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		if got := placeholderRanges(tt.content); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: placeholderRanges() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
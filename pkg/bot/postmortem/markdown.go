@@ -0,0 +1,212 @@
+package postmortem
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMarkdownTemplate is the template used when a team doesn't name one
+const DefaultMarkdownTemplate = "postmortem.md"
+
+// fieldCreated is the front matter key of when a Markdown postmortem was created
+const fieldCreated = "created"
+
+// Markdown writes every postmortem to its own Markdown file, named after the
+// date and title, so postmortems can be reviewed and kept in git. The fields
+// are kept in the file's front matter:
+//
+//	---
+//	status: "In Progress"
+//	title: "Service outage"
+//	---
+//
+// The body is filled in from the template once, after that it belongs to
+// whoever writes the postmortem.
+type Markdown struct {
+	templates string
+	dir       string
+	baseURL   string
+}
+
+// NewMarkdown returns a store reading templates from the templates directory
+// and writing postmortems to dir. Links point at baseURL, e.g. the folder on
+// GitHub, or at the file when it is empty.
+func NewMarkdown(templates, dir, baseURL string) *Markdown {
+	return &Markdown{templates: templates, dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Create fills in the template file of the name and writes it to a new file.
+func (m *Markdown) Create(template string, fields map[string]string) (Postmortem, error) {
+	if template == "" {
+		template = DefaultMarkdownTemplate
+	}
+	content, err := ioutil.ReadFile(filepath.Join(m.templates, filepath.Base(template)))
+	if err != nil {
+		return Postmortem{}, fmt.Errorf("failed to read postmortem template %v", err)
+	}
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return Postmortem{}, fmt.Errorf("failed to create postmortem folder %v", err)
+	}
+
+	created := time.Now()
+	base := fmt.Sprintf("%s-%s", created.Format("2006-01-02"), slug(fields[FieldTitle]))
+	id := base + ".md"
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(m.dir, id)); os.IsNotExist(err) {
+			break
+		}
+		id = fmt.Sprintf("%s-%d.md", base, i)
+	}
+
+	front := map[string]string{fieldCreated: created.Format(time.RFC3339)}
+	for k, v := range fields {
+		front[k] = v
+	}
+	if err := m.write(id, front, fill(string(content), fields)); err != nil {
+		return Postmortem{}, err
+	}
+
+	return m.postmortem(id, front), nil
+}
+
+// Update changes the fields in the front matter of the postmortem.
+func (m *Markdown) Update(id string, fields map[string]string) error {
+	front, body, err := m.read(id)
+	if err != nil {
+		return err
+	}
+	for k, v := range fields {
+		front[k] = v
+	}
+	return m.write(id, front, body)
+}
+
+// URL returns the link to the postmortem's file.
+func (m *Markdown) URL(id string) (string, error) {
+	if _, err := os.Stat(filepath.Join(m.dir, filepath.Base(id))); err != nil {
+		return "", fmt.Errorf("no postmortem %s", id)
+	}
+	return m.url(id), nil
+}
+
+// List returns the postmortems in the folder.
+func (m *Markdown) List() ([]Postmortem, error) {
+	entries, err := ioutil.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list postmortems %v", err)
+	}
+
+	var postmortems []Postmortem
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".md") {
+			continue
+		}
+		front, _, err := m.read(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		p := m.postmortem(e.Name(), front)
+		if p.Created.IsZero() {
+			p.Created = e.ModTime()
+		}
+		postmortems = append(postmortems, p)
+	}
+
+	sort.SliceStable(postmortems, func(i, j int) bool { return postmortems[i].Created.After(postmortems[j].Created) })
+	return postmortems, nil
+}
+
+func (m *Markdown) postmortem(id string, front map[string]string) Postmortem {
+	p := Postmortem{ID: id, Title: front[FieldTitle], URL: m.url(id), Fields: map[string]string{}}
+	for k, v := range front {
+		if k == fieldCreated {
+			p.Created, _ = time.Parse(time.RFC3339, v)
+			continue
+		}
+		p.Fields[k] = v
+	}
+	if p.Title == "" {
+		p.Title = strings.TrimSuffix(id, filepath.Ext(id))
+	}
+	return p
+}
+
+func (m *Markdown) url(id string) string {
+	if m.baseURL == "" {
+		return filepath.Join(m.dir, id)
+	}
+	return m.baseURL + "/" + id
+}
+
+// read splits the postmortem file into its front matter and body
+func (m *Markdown) read(id string) (map[string]string, string, error) {
+	content, err := ioutil.ReadFile(filepath.Join(m.dir, filepath.Base(id)))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read postmortem %s %v", id, err)
+	}
+
+	front := map[string]string{}
+	text := string(content)
+	if !strings.HasPrefix(text, "---\n") {
+		return front, text, nil
+	}
+	end := strings.Index(text[4:], "\n---\n")
+	if end < 0 {
+		return front, text, nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text[4 : 4+end]))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		if strings.HasPrefix(value, `"`) {
+			// written by write, anything else is taken as is
+			_ = json.Unmarshal([]byte(value), &value)
+		}
+		front[strings.TrimSpace(parts[0])] = value
+	}
+	return front, text[4+end+5:], nil
+}
+
+// write saves the postmortem with its front matter keys sorted, to keep diffs small
+func (m *Markdown) write(id string, front map[string]string, body string) error {
+	keys := make([]string, 0, len(front))
+	for k := range front {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	for _, k := range keys {
+		// A JSON string is a valid YAML string
+		value, _ := json.Marshal(front[k])
+		fmt.Fprintf(&b, "%s: %s\n", k, value)
+	}
+	b.WriteString("---\n")
+	b.WriteString(body)
+
+	path := filepath.Join(m.dir, filepath.Base(id))
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write postmortem %s %v", id, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write postmortem %s %v", id, err)
+	}
+	return nil
+}
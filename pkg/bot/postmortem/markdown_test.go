@@ -0,0 +1,211 @@
+package postmortem
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestMarkdown returns a store in a temporary folder with the template
+func newTestMarkdown(t *testing.T, template string) *Markdown {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "postmortem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	templates := filepath.Join(dir, "templates")
+	if err := os.MkdirAll(templates, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(templates, DefaultMarkdownTemplate), []byte(template), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewMarkdown(templates, filepath.Join(dir, "postmortems"), "https://example.com/postmortems/")
+}
+
+func TestMarkdownCreate(t *testing.T) {
+	m := newTestMarkdown(t, "# {{title}}\n\nStatus: {{ Status }}, owner {{owner}}\n")
+
+	p, err := m.Create("", map[string]string{
+		FieldTitle:  "Database: failover",
+		FieldStatus: `Draft "1"`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(p.ID, "-database-failover.md") {
+		t.Errorf("ID = %q, want it to end in -database-failover.md", p.ID)
+	}
+	if want := "https://example.com/postmortems/" + p.ID; p.URL != want {
+		t.Errorf("URL = %q, want %q", p.URL, want)
+	}
+	if p.Title != "Database: failover" {
+		t.Errorf("Title = %q, want the title field", p.Title)
+	}
+	if p.Created.IsZero() {
+		t.Error("Created is zero")
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(m.dir, p.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := "# Database: failover\n\nStatus: Draft \"1\", owner {{owner}}\n"
+	if !strings.HasSuffix(string(content), "---\n"+body) {
+		t.Errorf("file is\n%s\nwant the body\n%s", content, body)
+	}
+}
+
+func TestMarkdownCreateNumbersDuplicates(t *testing.T) {
+	m := newTestMarkdown(t, "{{title}}")
+
+	first, err := m.Create("", map[string]string{FieldTitle: "Outage"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := m.Create("", map[string]string{FieldTitle: "Outage"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.ID == second.ID {
+		t.Fatalf("both postmortems got the ID %q", first.ID)
+	}
+	if !strings.HasSuffix(second.ID, "-outage-2.md") {
+		t.Errorf("second ID = %q, want it to end in -outage-2.md", second.ID)
+	}
+}
+
+func TestMarkdownUpdate(t *testing.T) {
+	m := newTestMarkdown(t, "Status: {{status}}\nThe draft was written by the team.\n")
+
+	p, err := m.Create("", map[string]string{FieldTitle: "Outage", FieldStatus: "Draft"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Update(p.ID, map[string]string{FieldStatus: "In Review", FieldReviewer: "Alice: SRE"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Get(m, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{FieldTitle: "Outage", FieldStatus: "In Review", FieldReviewer: "Alice: SRE"}
+	for k, v := range want {
+		if got.Fields[k] != v {
+			t.Errorf("field %s = %q, want %q", k, got.Fields[k], v)
+		}
+	}
+	if !got.Created.Equal(p.Created) {
+		t.Errorf("Created = %v, want %v", got.Created, p.Created)
+	}
+
+	// Only the front matter changes, the body belongs to the authors
+	_, body, err := m.read(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Status: Draft\nThe draft was written by the team.\n"; body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestMarkdownList(t *testing.T) {
+	m := newTestMarkdown(t, "{{title}}")
+
+	all, err := m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 0 {
+		t.Fatalf("List() of a missing folder = %v, want nothing", all)
+	}
+
+	for _, title := range []string{"First", "Second"} {
+		if _, err := m.Create("", map[string]string{FieldTitle: title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Files that aren't postmortems are left out
+	if err := ioutil.WriteFile(filepath.Join(m.dir, "notes.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err = m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("List() returned %d postmortems, want 2", len(all))
+	}
+	if all[0].Created.Before(all[1].Created) {
+		t.Errorf("List() isn't sorted newest first: %v before %v", all[0].Created, all[1].Created)
+	}
+}
+
+func TestMarkdownURL(t *testing.T) {
+	m := newTestMarkdown(t, "{{title}}")
+
+	if _, err := m.URL("missing.md"); err == nil {
+		t.Error("URL of a missing postmortem didn't fail")
+	}
+
+	p, err := m.Create("", map[string]string{FieldTitle: "Outage"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := m.URL(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != p.URL {
+		t.Errorf("URL = %q, want %q", url, p.URL)
+	}
+}
+
+func TestMarkdownCreateMissingTemplate(t *testing.T) {
+	m := newTestMarkdown(t, "{{title}}")
+
+	if _, err := m.Create("other.md", map[string]string{FieldTitle: "Outage"}); err == nil {
+		t.Error("Create with a missing template didn't fail")
+	}
+}
+
+func TestFill(t *testing.T) {
+	tests := []struct {
+		text   string
+		fields map[string]string
+		want   string
+	}{
+		{"{{title}}", map[string]string{"title": "Outage"}, "Outage"},
+		{"{{ Title }} on {{date}}", map[string]string{"title": "Outage", "date": "today"}, "Outage on today"},
+		{"{{unknown}} stays", map[string]string{"title": "Outage"}, "{{unknown}} stays"},
+		{"{{Team}}", map[string]string{"TEAM": "SRE"}, "SRE"},
+		{"no placeholders", nil, "no placeholders"},
+	}
+	for _, tt := range tests {
+		if got := fill(tt.text, tt.fields); got != tt.want {
+			t.Errorf("fill(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := map[string]string{
+		"Service outage":        "service-outage",
+		"  DB: fail-over!! ":    "db-fail-over",
+		"Ünicode & émojis 🚀 ok": "nicode-mojis-ok",
+		"---":                   "",
+	}
+	for title, want := range tests {
+		if got := slug(title); got != want {
+			t.Errorf("slug(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
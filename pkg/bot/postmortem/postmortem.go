@@ -0,0 +1,93 @@
+// Package postmortem writes postmortem documents from templates, either to
+// Google Docs or to Markdown files kept in a git repository.
+package postmortem
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Fields every postmortem is created with. Templates refer to fields as
+// {{name}}, e.g. {{title}}, which is replaced by the field's value.
+const (
+	FieldTitle    = "title"
+	FieldDate     = "date"
+	FieldStatus   = "status"
+	FieldTeam     = "team"
+	FieldReviewer = "reviewer"
+	FieldIncident = "incident" // only set for postmortems of incidents
+	FieldSeverity = "severity" // only set for postmortems of incidents
+	FieldDue      = "due"      // only set when the incident's severity mandates a postmortem
+)
+
+// Postmortem is a postmortem document.
+type Postmortem struct {
+	ID      string // identifies the document in its store
+	Title   string
+	Created time.Time
+	URL     string
+	Fields  map[string]string // the values of the template fields
+}
+
+// A Store creates and keeps track of postmortem documents.
+type Store interface {
+	// Create writes a new postmortem from the template, replacing the fields.
+	// What the template names depends on the store, e.g. a Google Docs file ID.
+	Create(template string, fields map[string]string) (Postmortem, error)
+	// Update changes fields of an existing postmortem.
+	Update(id string, fields map[string]string) error
+	// URL returns the link to the postmortem.
+	URL(id string) (string, error)
+	// List returns every postmortem, newest first.
+	List() ([]Postmortem, error)
+}
+
+// Get returns the postmortem with the ID from the store.
+func Get(store Store, id string) (Postmortem, error) {
+	all, err := store.List()
+	if err != nil {
+		return Postmortem{}, err
+	}
+	for _, p := range all {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return Postmortem{}, fmt.Errorf("no postmortem %s", id)
+}
+
+// placeholder matches a field in a template, e.g. {{title}}
+var placeholder = regexp.MustCompile(`{{\s*([A-Za-z0-9_-]+)\s*}}`)
+
+// fill replaces the fields in text, placeholders of unknown fields are kept
+func fill(text string, fields map[string]string) string {
+	lower := make(map[string]string, len(fields))
+	for k, v := range fields {
+		lower[strings.ToLower(k)] = v
+	}
+	return placeholder.ReplaceAllStringFunc(text, func(m string) string {
+		if v, ok := lower[strings.ToLower(placeholder.FindStringSubmatch(m)[1])]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// slug turns a title into something safe for file and channel names
+func slug(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
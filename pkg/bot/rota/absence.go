@@ -0,0 +1,136 @@
+package rota
+
+import (
+	"sort"
+	"time"
+)
+
+// Absence is time someone is away according to their own calendar.
+type Absence struct {
+	Email   string
+	Summary string
+	Start   time.Time
+	End     time.Time
+}
+
+// An AbsenceSource knows when people are away.
+type AbsenceSource interface {
+	// Absences returns the absences of the person that overlap [from, to).
+	Absences(email string, from, to time.Time) ([]Absence, error)
+}
+
+// Conflict is a shift whose person is away for some of it.
+type Conflict struct {
+	Shift   Shift
+	Absence Absence
+}
+
+// Conflicts returns the shifts of the team between from and to that clash
+// with an absence of the person on call. The parts of regular shifts that an
+// override covers are not checked, someone else is on call then.
+func Conflicts(p Provider, src AbsenceSource, team string, from, to time.Time) ([]Conflict, error) {
+	shifts, err := p.Shifts(team, from, to)
+	if err != nil {
+		return nil, err
+	}
+	shifts = Resolve(shifts)
+	sort.SliceStable(shifts, func(i, j int) bool { return shifts[i].Start.Before(shifts[j].Start) })
+
+	if len(shifts) == 0 {
+		return nil, nil
+	}
+
+	// Shifts may start before from and end after to
+	last := shifts[0].End
+	for _, s := range shifts {
+		if s.End.After(last) {
+			last = s.End
+		}
+	}
+
+	absences := newAbsenceCache(src, shifts[0].Start, last)
+	var conflicts []Conflict
+	for _, s := range shifts {
+		if s.Email == "" {
+			continue
+		}
+
+		away, err := absences.during(s.Email, s.Start, s.End)
+		if err != nil {
+			return nil, err
+		}
+		if len(away) > 0 {
+			conflicts = append(conflicts, Conflict{Shift: s, Absence: away[0]})
+		}
+	}
+
+	return conflicts, nil
+}
+
+// Replacements suggests members who could take the shift instead: they are
+// neither declared unavailable nor away according to their calendar, and
+// don't hold another shift of the team at the time. The members with the
+// fewest shifts in the four weeks either side of the shift come first.
+func Replacements(p Provider, src AbsenceSource, shift Shift, members []Member) ([]string, error) {
+	around := 28 * 24 * time.Hour
+	shifts, err := p.Shifts(shift.Team, shift.Start.Add(-around), shift.End.Add(around))
+	if err != nil {
+		return nil, err
+	}
+
+	load := map[string]int{}
+	for _, s := range shifts {
+		load[s.Email]++
+	}
+
+	absences := newAbsenceCache(src, shift.Start, shift.End)
+	var candidates []string
+	for _, m := range members {
+		if m.Email == shift.Email || !m.available(shift.Start, shift.End) || busy(shifts, m.Email, shift.Start, shift.End) {
+			continue
+		}
+
+		away, err := absences.during(m.Email, shift.Start, shift.End)
+		if err != nil {
+			return nil, err
+		}
+		if len(away) == 0 {
+			candidates = append(candidates, m.Email)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return load[candidates[i]] < load[candidates[j]] })
+	return candidates, nil
+}
+
+// absenceCache looks up the absences of each person between from and to
+// only once.
+type absenceCache struct {
+	src      AbsenceSource
+	from, to time.Time
+	absences map[string][]Absence
+}
+
+func newAbsenceCache(src AbsenceSource, from, to time.Time) *absenceCache {
+	return &absenceCache{src: src, from: from, to: to, absences: map[string][]Absence{}}
+}
+
+// during returns the absences of email overlapping [from, to), which must
+// lie within the range of the cache.
+func (c *absenceCache) during(email string, from, to time.Time) ([]Absence, error) {
+	if _, ok := c.absences[email]; !ok {
+		absences, err := c.src.Absences(email, c.from, c.to)
+		if err != nil {
+			return nil, err
+		}
+		c.absences[email] = absences
+	}
+
+	var overlapping []Absence
+	for _, a := range c.absences[email] {
+		if a.Start.Before(to) && a.End.After(from) {
+			overlapping = append(overlapping, a)
+		}
+	}
+	return overlapping, nil
+}
@@ -0,0 +1,63 @@
+package rota
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeAbsences is an AbsenceSource of a fixed list of absences
+type fakeAbsences []Absence
+
+func (f fakeAbsences) Absences(email string, from, to time.Time) ([]Absence, error) {
+	var absences []Absence
+	for _, a := range f {
+		if a.Email == email && a.Start.Before(to) && a.End.After(from) {
+			absences = append(absences, a)
+		}
+	}
+	return absences, nil
+}
+
+func TestConflicts(t *testing.T) {
+	away := fakeAbsences{{Email: "alice", Summary: "OOO", Start: june(17, 0), End: june(18, 0)}}
+	week := Shift{Tier: "L2", Email: "alice", Start: june(15, 0), End: june(22, 0)}
+
+	tests := []struct {
+		name   string
+		shifts []Shift
+		want   []string // the email and start of the conflicting shifts
+	}{
+		{
+			name:   "away during the shift",
+			shifts: []Shift{week},
+			want:   []string{"alice " + june(15, 0).String()},
+		},
+		{
+			name:   "covered while away",
+			shifts: []Shift{week, {Tier: "L2", Email: "bob", Start: june(16, 0), End: june(19, 0), Override: true}},
+		},
+		{
+			name:   "covered on other days",
+			shifts: []Shift{week, {Tier: "L2", Email: "bob", Start: june(19, 0), End: june(20, 0), Override: true}},
+			want:   []string{"alice " + june(15, 0).String()},
+		},
+		{
+			name:   "covering person is away",
+			shifts: []Shift{{Tier: "L2", Email: "alice", Start: june(16, 0), End: june(19, 0), Override: true}},
+			want:   []string{"alice " + june(16, 0).String()},
+		},
+	}
+	for _, tt := range tests {
+		conflicts, err := Conflicts(NewMemoryProvider(tt.shifts...), away, DefaultTeam, june(15, 0), june(22, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, c := range conflicts {
+			got = append(got, c.Shift.Email+" "+c.Shift.Start.String())
+		}
+		if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("%s: Conflicts() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package rota
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Problem is something wrong with a rota that needs a person to fix it.
+type Problem struct {
+	Team        string
+	Tier        string
+	Start       time.Time
+	Description string
+}
+
+// An Inspector is a Provider that can report problems with its source which
+// never make it into a Shift, such as calendar events without attendees.
+type Inspector interface {
+	Provider
+
+	Inspect(team string, from, to time.Time) ([]Problem, error)
+}
+
+// CheckOptions control which problems Check reports.
+type CheckOptions struct {
+	Weekends bool // report tiers with daily or shorter windows left uncovered on Saturday and Sunday
+}
+
+// Check looks for windows of each tier that nobody covers, regular shifts of
+// the same tier that overlap and shifts without a person between from and to.
+// Problems reported by an Inspector are included. The result is sorted by time.
+func Check(p Provider, team string, tiers []Tier, from, to time.Time, opts CheckOptions) ([]Problem, error) {
+	shifts, err := p.Shifts(team, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	if inspector, ok := p.(Inspector); ok {
+		problems, err = inspector.Inspect(team, from, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, tier := range tiers {
+		problems = append(problems, uncovered(shifts, team, tier, from, to, opts)...)
+		problems = append(problems, overlapping(shifts, team, tier)...)
+	}
+
+	for _, s := range shifts {
+		if s.Email == "" {
+			problems = append(problems, Problem{
+				Team:        team,
+				Tier:        s.Tier,
+				Start:       s.Start,
+				Description: fmt.Sprintf("%s shift starting %s has nobody assigned", s.Tier, s.Start.Format("Mon 2 Jan 15:04")),
+			})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Start.Before(problems[j].Start) })
+	return problems, nil
+}
+
+func uncovered(shifts []Shift, team string, tier Tier, from, to time.Time, opts CheckOptions) []Problem {
+	var problems []Problem
+	for at := from; at.Before(to); {
+		start, end := tier.Window.Around(at)
+		at = end.Add(time.Nanosecond)
+
+		weekend := start.Weekday() == time.Saturday || start.Weekday() == time.Sunday
+		if weekend && !opts.Weekends && end.Sub(start) <= 24*time.Hour {
+			continue
+		}
+
+		if pick(shifts, tier, start) == nil {
+			problems = append(problems, Problem{
+				Team:        team,
+				Tier:        tier.Name,
+				Start:       start,
+				Description: fmt.Sprintf("nobody is on %s %s", tier.Name, tier.Window.DescribeAt(start, time.Time{})),
+			})
+		}
+	}
+	return problems
+}
+
+func overlapping(shifts []Shift, team string, tier Tier) []Problem {
+	var regular []Shift
+	for _, s := range shifts {
+		if s.Tier == tier.Name && !s.Override {
+			regular = append(regular, s)
+		}
+	}
+	sort.Slice(regular, func(i, j int) bool { return regular[i].Start.Before(regular[j].Start) })
+
+	var problems []Problem
+	for i := 1; i < len(regular); i++ {
+		prev, cur := regular[i-1], regular[i]
+		if cur.Start.Before(prev.End) {
+			problems = append(problems, Problem{
+				Team:  team,
+				Tier:  tier.Name,
+				Start: cur.Start,
+				Description: fmt.Sprintf("%s shifts of %s and %s overlap on %s",
+					tier.Name, prev.Email, cur.Email, cur.Start.Format("Mon 2 Jan")),
+			})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,105 @@
+package rota
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// FileProvider serves the shifts listed in a static JSON or YAML file:
+//
+//	shifts:
+//	  - team: default
+//	    tier: L1
+//	    email: alice@example.com
+//	    start: 2020-06-15
+//	    end: 2020-06-15
+//
+// Start and end are either RFC 3339 timestamps or dates. A date end is
+// inclusive, so the example above covers the whole of the 15th in the team's
+// time zone.
+type FileProvider struct {
+	shifts    *MemoryProvider
+	path      string
+	locations Locations
+}
+
+type fileShift struct {
+	Team  string
+	Tier  string
+	Email string
+	Start string
+	End   string
+}
+
+// NewFileProvider reads the shifts from the file at path.
+func NewFileProvider(path string, locations Locations) (*FileProvider, error) {
+	p := &FileProvider{
+		shifts:    NewMemoryProvider(),
+		path:      path,
+		locations: locations,
+	}
+	if err := p.Load(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Load (re)reads the shifts from the file.
+func (p *FileProvider) Load() error {
+	v := viper.New()
+	v.SetConfigFile(p.path)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read rota file %s: %w", p.path, err)
+	}
+
+	var raw []fileShift
+	if err := v.UnmarshalKey("shifts", &raw); err != nil {
+		return fmt.Errorf("failed to parse rota file %s: %w", p.path, err)
+	}
+
+	shifts := make([]Shift, 0, len(raw))
+	for i, r := range raw {
+		team := r.Team
+		if team == "" {
+			team = DefaultTeam
+		}
+		loc := p.locations.Of(team)
+
+		start, _, err := parseFileTime(r.Start, loc)
+		if err != nil {
+			return fmt.Errorf("shift %d start: %w", i, err)
+		}
+		end, dateOnly, err := parseFileTime(r.End, loc)
+		if err != nil {
+			return fmt.Errorf("shift %d end: %w", i, err)
+		}
+		if dateOnly {
+			end = end.AddDate(0, 0, 1)
+		}
+
+		shifts = append(shifts, Shift{
+			Team:  team,
+			Tier:  r.Tier,
+			Email: r.Email,
+			Start: start,
+			End:   end,
+		})
+	}
+
+	p.shifts.Replace(shifts...)
+	return nil
+}
+
+func (p *FileProvider) Shifts(team string, from, to time.Time) ([]Shift, error) {
+	return p.shifts.Shifts(team, from, to)
+}
+
+func parseFileTime(s string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t, false, err
+}
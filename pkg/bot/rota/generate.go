@@ -0,0 +1,185 @@
+package rota
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Member is someone who can be put on the rota.
+type Member struct {
+	Email       string
+	Unavailable []Period
+}
+
+// available reports whether the member can be on call between from and to.
+func (m Member) available(from, to time.Time) bool {
+	for _, p := range m.Unavailable {
+		if p.From.Before(to) && p.To.After(from) {
+			return false
+		}
+	}
+	return true
+}
+
+// GenerateOptions control how Generate builds a rota.
+type GenerateOptions struct {
+	Weekends bool    // also fill windows of daily or shorter tiers on Saturday and Sunday
+	Existing []Shift // shifts already on the rota, their windows are kept as they are
+}
+
+// Generate builds a rota for the tiers of a team for the given number of
+// weeks starting at from. Weekly windows are counted from from rather than
+// from the start of the calendar week, other windows start with the one that
+// contains from, cut to begin at from. Members are assigned round robin while
+//
+//   - nobody is put on a window they declared unavailable for,
+//   - nobody holds two tiers at once,
+//   - nobody takes the same week-or-longer tier twice in a row,
+//   - shifts and weekend days are spread as evenly as possible.
+//
+// Windows of a tier that already have a shift in opts.Existing are skipped,
+// the people on existing shifts count as busy. A window nobody can take is
+// left empty, Check reports those gaps.
+func Generate(team string, tiers []Tier, members []Member, from time.Time, weeks int, opts GenerateOptions) ([]Shift, error) {
+	if len(members) == 0 {
+		return nil, errors.New("the team has no members to put on the rota")
+	}
+	if weeks < 1 {
+		return nil, fmt.Errorf("cannot generate a rota for %d weeks", weeks)
+	}
+
+	until := from.AddDate(0, 0, 7*weeks)
+	stats := make([]memberStats, len(members))
+	for i := range stats {
+		stats[i] = memberStats{tierShifts: map[string]int{}, lastShift: -1}
+	}
+
+	// Fill the longest windows first so people on a weekly tier are kept
+	// off the daily tiers of that week rather than the other way around.
+	ordered := append([]Tier(nil), tiers...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return windowLength(ordered[i], from) > windowLength(ordered[j], from)
+	})
+
+	var shifts []Shift
+	turn := 0
+	for _, tier := range ordered {
+		var previous = -1
+		for at := from; at.Before(until); {
+			start, end := tier.Window.Around(at)
+			switch tier.Window {
+			case Week:
+				start, end = at, at.AddDate(0, 0, 7)
+			case Day, Month:
+				end = end.Add(time.Nanosecond) // calendar windows end inclusively, shifts do not
+			}
+			if start.Before(from) {
+				start = from
+			}
+			at = end
+
+			if taken(opts.Existing, tier.Name, start, end) {
+				previous = -1
+				continue
+			}
+
+			weekendDays := countWeekendDays(start, end)
+			if weekendDays > 0 && !opts.Weekends && end.Sub(start) <= 24*time.Hour {
+				continue
+			}
+
+			candidates := make([]int, 0, len(members))
+			for i, m := range members {
+				if !m.available(start, end) || busy(shifts, m.Email, start, end) || busy(opts.Existing, m.Email, start, end) {
+					continue
+				}
+				if i == previous && end.Sub(start) >= 7*24*time.Hour {
+					continue
+				}
+				candidates = append(candidates, i)
+			}
+			if len(candidates) == 0 {
+				previous = -1
+				continue
+			}
+
+			sort.SliceStable(candidates, func(a, b int) bool {
+				sa, sb := stats[candidates[a]], stats[candidates[b]]
+				if sa.tierShifts[tier.Name] != sb.tierShifts[tier.Name] {
+					return sa.tierShifts[tier.Name] < sb.tierShifts[tier.Name]
+				}
+				if weekendDays > 0 && sa.weekendDays != sb.weekendDays {
+					return sa.weekendDays < sb.weekendDays
+				}
+				if sa.shifts != sb.shifts {
+					return sa.shifts < sb.shifts
+				}
+				return sa.lastShift < sb.lastShift
+			})
+
+			chosen := candidates[0]
+			stats[chosen].tierShifts[tier.Name]++
+			stats[chosen].shifts++
+			stats[chosen].weekendDays += weekendDays
+			stats[chosen].lastShift = turn
+			turn++
+			previous = chosen
+
+			shifts = append(shifts, Shift{
+				Team:  team,
+				Tier:  tier.Name,
+				Email: members[chosen].Email,
+				Start: start,
+				End:   end,
+			})
+		}
+	}
+
+	sort.SliceStable(shifts, func(i, j int) bool { return shifts[i].Start.Before(shifts[j].Start) })
+	return shifts, nil
+}
+
+type memberStats struct {
+	tierShifts  map[string]int
+	shifts      int
+	weekendDays int
+	lastShift   int
+}
+
+func windowLength(tier Tier, at time.Time) time.Duration {
+	start, end := tier.Window.Around(at)
+	return end.Sub(start)
+}
+
+// busy reports whether email already holds a shift overlapping from and to.
+func busy(shifts []Shift, email string, from, to time.Time) bool {
+	for _, s := range shifts {
+		if s.Email == email && s.Overlaps(from, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// taken reports whether a shift of the tier overlaps from and to.
+func taken(shifts []Shift, tier string, from, to time.Time) bool {
+	for _, s := range shifts {
+		if s.Tier == tier && s.Overlaps(from, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// countWeekendDays counts the Saturdays and Sundays that [from, to) touches.
+func countWeekendDays(from, to time.Time) int {
+	days := 0
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			days++
+		}
+	}
+	return days
+}
@@ -0,0 +1,158 @@
+package rota
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerate(t *testing.T) {
+	l1 := Tier{Name: "L1", Prefix: "L1:", Window: Day}
+	l2 := Tier{Name: "L2", Prefix: "L2:", Window: Week}
+	members := []Member{{Email: "alice"}, {Email: "bob"}, {Email: "carol"}}
+	from := june(18, 10) // a Thursday
+
+	tests := []struct {
+		name    string
+		tiers   []Tier
+		members []Member
+		weeks   int
+		opts    GenerateOptions
+		check   func(t *testing.T, shifts []Shift)
+	}{
+		{
+			name:    "weeks start at from",
+			tiers:   []Tier{l2},
+			members: members,
+			weeks:   2,
+			check: func(t *testing.T, shifts []Shift) {
+				if len(shifts) != 2 {
+					t.Fatalf("got %d shifts, want 2", len(shifts))
+				}
+				for i, s := range shifts {
+					if start := from.AddDate(0, 0, 7*i); !s.Start.Equal(start) || !s.End.Equal(start.AddDate(0, 0, 7)) {
+						t.Errorf("shift %d is %v - %v, want the week from %v", i, s.Start, s.End, start)
+					}
+				}
+				if shifts[0].Email == shifts[1].Email {
+					t.Errorf("%s is on call two weeks in a row", shifts[0].Email)
+				}
+			},
+		},
+		{
+			name:    "nobody holds two tiers at once",
+			tiers:   []Tier{l1, l2},
+			members: members,
+			weeks:   2,
+			opts:    GenerateOptions{Weekends: true},
+			check: func(t *testing.T, shifts []Shift) {
+				days := 0
+				for i, s := range shifts {
+					if s.Start.Before(from) {
+						t.Errorf("%s shift starts at %v, before %v", s.Tier, s.Start, from)
+					}
+					if s.Tier == "L1" {
+						days++
+					}
+					for _, other := range shifts[i+1:] {
+						if s.Email == other.Email && s.Overlaps(other.Start, other.End) {
+							t.Errorf("%s holds %s and %s at %v", s.Email, s.Tier, other.Tier, other.Start)
+						}
+					}
+				}
+				if days != 15 {
+					t.Errorf("got %d L1 shifts, want 15", days)
+				}
+			},
+		},
+		{
+			name:    "weekends are left out",
+			tiers:   []Tier{l1},
+			members: members,
+			weeks:   1,
+			check: func(t *testing.T, shifts []Shift) {
+				for _, s := range shifts {
+					if wd := s.Start.Weekday(); wd == time.Saturday || wd == time.Sunday {
+						t.Errorf("%s is on call on %s", s.Email, wd)
+					}
+				}
+			},
+		},
+		{
+			name:  "unavailable members are skipped",
+			tiers: []Tier{l1},
+			members: []Member{
+				{Email: "alice", Unavailable: []Period{day(june(22, 0)), day(june(23, 0))}},
+				{Email: "bob"},
+			},
+			weeks: 1,
+			opts:  GenerateOptions{Weekends: true},
+			check: func(t *testing.T, shifts []Shift) {
+				for _, s := range shifts {
+					if s.Email == "alice" && s.Overlaps(june(22, 0), june(24, 0)) {
+						t.Errorf("alice is on call on %v while unavailable", s.Start)
+					}
+				}
+			},
+		},
+		{
+			name:    "existing shifts are kept",
+			tiers:   []Tier{l1, l2},
+			members: members,
+			weeks:   1,
+			opts: GenerateOptions{Weekends: true, Existing: []Shift{
+				{Tier: "L2", Email: "alice", Start: from, End: from.AddDate(0, 0, 7)},
+				{Tier: "L1", Email: "bob", Start: june(19, 0), End: june(20, 0)},
+			}},
+			check: func(t *testing.T, shifts []Shift) {
+				for _, s := range shifts {
+					if s.Tier == "L2" {
+						t.Errorf("generated an L2 shift %v over the existing one", s.Start)
+					}
+					if s.Email == "alice" {
+						t.Errorf("alice is on L1 at %v while on the existing L2 shift", s.Start)
+					}
+					if s.Overlaps(june(19, 0), june(20, 0)) {
+						t.Errorf("generated an L1 shift %v over the existing one", s.Start)
+					}
+				}
+				if len(shifts) != 7 {
+					t.Errorf("got %d shifts, want the 7 other L1 days", len(shifts))
+				}
+			},
+		},
+		{
+			name:    "windows nobody can take stay empty",
+			tiers:   []Tier{l2},
+			members: []Member{{Email: "alice", Unavailable: []Period{{From: june(20, 0), To: june(21, 0)}}}},
+			weeks:   2,
+			check: func(t *testing.T, shifts []Shift) {
+				if len(shifts) != 1 || !shifts[0].Start.Equal(from.AddDate(0, 0, 7)) {
+					t.Errorf("got %v, want only the second week", shifts)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shifts, err := Generate("sre", tt.tiers, tt.members, from, tt.weeks, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range shifts {
+				if s.Team != "sre" || s.Override {
+					t.Errorf("got shift %+v, want a regular shift of sre", s)
+				}
+			}
+			tt.check(t, shifts)
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	if _, err := Generate("sre", DefaultTiers, nil, june(18, 0), 1, GenerateOptions{}); err == nil {
+		t.Error("Generate without members didn't fail")
+	}
+	if _, err := Generate("sre", DefaultTiers, []Member{{Email: "alice"}}, june(18, 0), 0, GenerateOptions{}); err == nil {
+		t.Error("Generate for no weeks didn't fail")
+	}
+}
@@ -0,0 +1,316 @@
+package rota
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// overrideProperty marks the calendar events created by Override.
+const overrideProperty = "srebot-override"
+
+// GoogleProvider reads shifts from Google calendars, one calendar per team.
+// Each event whose summary starts with a tier prefix is a shift for the first
+// attendee of that event. All-day events cover the day in the team's zone.
+type GoogleProvider struct {
+	srv       *calendar.Service
+	calendars map[string]string
+	tiers     []Tier
+	locations Locations
+}
+
+// NewGoogleProvider returns a provider reading the calendars (team name to
+// calendar ID) with the given service.
+func NewGoogleProvider(srv *calendar.Service, calendars map[string]string, tiers []Tier, locations Locations) *GoogleProvider {
+	return &GoogleProvider{
+		srv:       srv,
+		calendars: calendars,
+		tiers:     tiers,
+		locations: locations,
+	}
+}
+
+func (p *GoogleProvider) Shifts(team string, from, to time.Time) ([]Shift, error) {
+	events, err := p.events(team, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var shifts []Shift
+	for _, e := range events {
+		tier, ok := tierFromSummary(p.tiers, e.Summary)
+		if !ok || len(e.Attendees) == 0 {
+			continue
+		}
+
+		start, end, err := EventTimes(e, p.locations.Of(team))
+		if err != nil {
+			return nil, err
+		}
+
+		shifts = append(shifts, Shift{
+			Team:     team,
+			Tier:     tier,
+			Email:    e.Attendees[0].Email,
+			Start:    start,
+			End:      end,
+			Override: e.ExtendedProperties != nil && e.ExtendedProperties.Private[overrideProperty] == "true",
+		})
+	}
+
+	return shifts, nil
+}
+
+// Inspect reports rota events without attendees, which are ignored, and
+// events with several attendees, of which only the first is on call.
+func (p *GoogleProvider) Inspect(team string, from, to time.Time) ([]Problem, error) {
+	events, err := p.events(team, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	for _, e := range events {
+		tier, ok := tierFromSummary(p.tiers, e.Summary)
+		if !ok || len(e.Attendees) == 1 {
+			continue
+		}
+
+		start, _, err := EventTimes(e, p.locations.Of(team))
+		if err != nil {
+			return nil, err
+		}
+
+		problem := Problem{Team: team, Tier: tier, Start: start}
+		if len(e.Attendees) == 0 {
+			problem.Description = fmt.Sprintf("event %q on %s has no attendees", e.Summary, start.Format("Mon 2 Jan"))
+		} else {
+			problem.Description = fmt.Sprintf("event %q on %s has %d attendees, only %s is on call",
+				e.Summary, start.Format("Mon 2 Jan"), len(e.Attendees), e.Attendees[0].Email)
+		}
+		problems = append(problems, problem)
+	}
+
+	return problems, nil
+}
+
+// events lists every event of the team's calendar between from and to.
+func (p *GoogleProvider) events(team string, from, to time.Time) ([]*calendar.Event, error) {
+	calendarID, ok := p.calendars[team]
+	if !ok {
+		return nil, fmt.Errorf("no rota calendar configured for team %q", team)
+	}
+
+	var events []*calendar.Event
+	err := p.srv.Events.
+		List(calendarID).
+		TimeMin(from.Format(time.RFC3339)).
+		TimeMax(to.Format(time.RFC3339)).
+		SingleEvents(true).
+		OrderBy("startTime").
+		Pages(context.Background(), func(page *calendar.Events) error {
+			events = append(events, page.Items...)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Create adds an event for the shift to the team's calendar.
+func (p *GoogleProvider) Create(shift Shift) error {
+	return p.insert(shift, false)
+}
+
+// Override creates an event on the team's calendar that assigns the shift.
+func (p *GoogleProvider) Override(shift Shift) error {
+	return p.insert(shift, true)
+}
+
+func (p *GoogleProvider) insert(shift Shift, override bool) error {
+	calendarID, ok := p.calendars[shift.Team]
+	if !ok {
+		return fmt.Errorf("no rota calendar configured for team %q", shift.Team)
+	}
+
+	var prefix string
+	for _, t := range p.tiers {
+		if t.Name == shift.Tier {
+			prefix = t.Prefix
+		}
+	}
+	if prefix == "" {
+		return fmt.Errorf("unknown tier %q", shift.Tier)
+	}
+
+	event := &calendar.Event{
+		Summary:     fmt.Sprintf("%s %s", prefix, shift.Email),
+		Description: "Created from Slack by the SRE bot",
+		Attendees:   []*calendar.EventAttendee{{Email: shift.Email}},
+		Start:       &calendar.EventDateTime{DateTime: shift.Start.Format(time.RFC3339)},
+		End:         &calendar.EventDateTime{DateTime: shift.End.Format(time.RFC3339)},
+	}
+	if override {
+		event.Summary += " (override)"
+		event.ExtendedProperties = &calendar.EventExtendedProperties{
+			Private: map[string]string{overrideProperty: "true"},
+		}
+	}
+
+	_, err := p.srv.Events.Insert(calendarID, event).Do()
+	return err
+}
+
+// DefaultAbsenceKeywords mark calendar events as time away when no keywords
+// are configured.
+var DefaultAbsenceKeywords = []string{"ooo", "out of office", "vacation", "holiday", "annual leave", "pto", "sick"}
+
+// GoogleAbsences reads absences from people's primary calendars, which the
+// calendar user must be able to see, e.g. through domain-wide sharing. Events
+// whose summary contains a keyword are absences, unless they are rota shifts:
+// events of a rota calendar or whose summary starts with a tier's prefix.
+type GoogleAbsences struct {
+	srv       *calendar.Service
+	keywords  []string
+	tiers     []Tier
+	calendars []string // IDs of the rota calendars
+}
+
+// NewGoogleAbsences returns an absence source reading calendars with the
+// given service, ignoring the shifts of the tiers and rota calendars.
+func NewGoogleAbsences(srv *calendar.Service, keywords []string, tiers []Tier, calendars []string) *GoogleAbsences {
+	if len(keywords) == 0 {
+		keywords = DefaultAbsenceKeywords
+	}
+	return &GoogleAbsences{srv: srv, keywords: keywords, tiers: tiers, calendars: calendars}
+}
+
+// Absences reads all-day events as the days in the time zone of from.
+func (a *GoogleAbsences) Absences(email string, from, to time.Time) ([]Absence, error) {
+	var absences []Absence
+	err := a.srv.Events.
+		List(email).
+		TimeMin(from.Format(time.RFC3339)).
+		TimeMax(to.Format(time.RFC3339)).
+		SingleEvents(true).
+		OrderBy("startTime").
+		Pages(context.Background(), func(page *calendar.Events) error {
+			for _, e := range page.Items {
+				if !a.isAbsence(email, e) {
+					continue
+				}
+
+				start, end, err := EventTimes(e, from.Location())
+				if err != nil {
+					return err
+				}
+				absences = append(absences, Absence{Email: email, Summary: e.Summary, Start: start, End: end})
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the calendar of %s: %w", email, err)
+	}
+
+	return absences, nil
+}
+
+func (a *GoogleAbsences) isAbsence(email string, e *calendar.Event) bool {
+	if e.Status == "cancelled" {
+		return false
+	}
+	for _, attendee := range e.Attendees {
+		if strings.EqualFold(attendee.Email, email) && attendee.ResponseStatus == "declined" {
+			return false
+		}
+	}
+
+	if _, ok := tierFromSummary(a.tiers, e.Summary); ok {
+		return false
+	}
+	for _, id := range a.calendars {
+		if id == "" {
+			continue
+		}
+		if (e.Organizer != nil && strings.EqualFold(e.Organizer.Email, id)) ||
+			(e.Creator != nil && strings.EqualFold(e.Creator.Email, id)) {
+			return false
+		}
+	}
+
+	summary := strings.ToLower(e.Summary)
+	for _, k := range a.keywords {
+		if strings.Contains(summary, strings.ToLower(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// GoogleHolidays reads public holidays from a Google calendar such as
+// en.uk#holiday@group.v.calendar.google.com, every event is a holiday.
+type GoogleHolidays struct {
+	srv        *calendar.Service
+	calendarID string
+}
+
+// NewGoogleHolidays returns a holiday source reading the calendar with the
+// given service.
+func NewGoogleHolidays(srv *calendar.Service, calendarID string) *GoogleHolidays {
+	return &GoogleHolidays{srv: srv, calendarID: calendarID}
+}
+
+// Holidays reads the holidays as the days in the time zone of from.
+func (h *GoogleHolidays) Holidays(from, to time.Time) ([]Holiday, error) {
+	var holidays []Holiday
+	err := h.srv.Events.
+		List(h.calendarID).
+		TimeMin(from.Format(time.RFC3339)).
+		TimeMax(to.Format(time.RFC3339)).
+		SingleEvents(true).
+		OrderBy("startTime").
+		Pages(context.Background(), func(page *calendar.Events) error {
+			for _, e := range page.Items {
+				start, end, err := EventTimes(e, from.Location())
+				if err != nil {
+					return err
+				}
+				holidays = append(holidays, holidayDays(e.Summary, start, end, from, to)...)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read holiday calendar %s: %w", h.calendarID, err)
+	}
+
+	return holidays, nil
+}
+
+// EventTimes returns the start and end of a timed or all-day calendar event,
+// the days of all-day events start at midnight in loc.
+func EventTimes(e *calendar.Event, loc *time.Location) (time.Time, time.Time, error) {
+	start, err := parseEventDateTime(e.Start, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("event %q start: %w", e.Summary, err)
+	}
+	end, err := parseEventDateTime(e.End, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("event %q end: %w", e.Summary, err)
+	}
+	return start, end, nil
+}
+
+func parseEventDateTime(dt *calendar.EventDateTime, loc *time.Location) (time.Time, error) {
+	if dt == nil {
+		return time.Time{}, fmt.Errorf("missing date")
+	}
+	if dt.DateTime != "" {
+		return time.Parse(time.RFC3339, dt.DateTime)
+	}
+	return time.ParseInLocation("2006-01-02", dt.Date, loc)
+}
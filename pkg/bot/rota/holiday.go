@@ -0,0 +1,141 @@
+package rota
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dombo/hiberBot/pkg/bot/ical"
+	"github.com/jinzhu/now"
+	"github.com/spf13/viper"
+)
+
+// Holiday is a public holiday, Date is midnight at its start in the time zone
+// it was looked up in.
+type Holiday struct {
+	Name string
+	Date time.Time
+}
+
+// A HolidaySource knows the public holidays of a region.
+type HolidaySource interface {
+	// Holidays returns the holidays on the days between from and to, the
+	// days are those of the time zone of from.
+	Holidays(from, to time.Time) ([]Holiday, error)
+}
+
+// HolidayOn returns the holiday on the day of t, or nil.
+func HolidayOn(src HolidaySource, t time.Time) (*Holiday, error) {
+	day := now.With(t).BeginningOfDay()
+	holidays, err := src.Holidays(day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range holidays {
+		if h.Date.Equal(day) {
+			return &h, nil
+		}
+	}
+	return nil, nil
+}
+
+// holidayDays returns a holiday for every day in the time zone of from that an
+// event spans and that overlaps [from, to).
+func holidayDays(name string, start, end time.Time, from, to time.Time) []Holiday {
+	var holidays []Holiday
+	for d := now.With(start.In(from.Location())).BeginningOfDay(); d.Before(end); d = d.AddDate(0, 0, 1) {
+		if d.Before(to) && d.AddDate(0, 0, 1).After(from) {
+			holidays = append(holidays, Holiday{Name: name, Date: d})
+		}
+	}
+	return holidays
+}
+
+// FileHolidays serves the holidays of an .ics file, where every event is a
+// holiday, or of a JSON or YAML file:
+//
+//	holidays:
+//	  - date: 2020-12-25
+//	    name: Christmas Day
+//
+// The holidays are dates, they fall on that day in every time zone.
+type FileHolidays struct {
+	holidays []Holiday // dates at midnight UTC
+}
+
+type fileHoliday struct {
+	Date string
+	Name string
+}
+
+// NewFileHolidays reads the holidays from the file at path.
+func NewFileHolidays(path string) (*FileHolidays, error) {
+	if strings.EqualFold(filepath.Ext(path), ".ics") {
+		return readICSHolidays(path)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read holiday file %s: %w", path, err)
+	}
+
+	var raw []fileHoliday
+	if err := v.UnmarshalKey("holidays", &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse holiday file %s: %w", path, err)
+	}
+
+	holidays := make([]Holiday, 0, len(raw))
+	for i, r := range raw {
+		date, err := time.Parse("2006-01-02", r.Date)
+		if err != nil {
+			return nil, fmt.Errorf("holiday %d: %w", i, err)
+		}
+		holidays = append(holidays, Holiday{Name: r.Name, Date: date})
+	}
+
+	return &FileHolidays{holidays: holidays}, nil
+}
+
+func readICSHolidays(path string) (*FileHolidays, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	events, err := ical.Parse(f, time.UTC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse holiday file %s: %w", path, err)
+	}
+
+	// Holidays such as Christmas are often yearly events, expand them well
+	// beyond any period the bot looks at.
+	from := time.Now().AddDate(-2, 0, 0)
+	occurrences, errs := ical.Expand(events, from, from.AddDate(5, 0, 0))
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to expand holiday file %s: %w", path, errs[0])
+	}
+
+	var holidays []Holiday
+	for _, e := range occurrences {
+		for _, h := range holidayDays(e.Summary, e.Start, e.End, e.Start, e.End) {
+			h.Date = time.Date(h.Date.Year(), h.Date.Month(), h.Date.Day(), 0, 0, 0, 0, time.UTC)
+			holidays = append(holidays, h)
+		}
+	}
+	return &FileHolidays{holidays: holidays}, nil
+}
+
+func (f *FileHolidays) Holidays(from, to time.Time) ([]Holiday, error) {
+	var holidays []Holiday
+	for _, h := range f.holidays {
+		h.Date = time.Date(h.Date.Year(), h.Date.Month(), h.Date.Day(), 0, 0, 0, 0, from.Location())
+		if h.Date.Before(to) && h.Date.AddDate(0, 0, 1).After(from) {
+			holidays = append(holidays, h)
+		}
+	}
+	return holidays, nil
+}
@@ -0,0 +1,67 @@
+package rota
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteICS writes the shifts as an RFC 5545 iCalendar feed named name.
+// Shifts spanning whole days are written as all-day events.
+func WriteICS(w io.Writer, name string, shifts []Shift) error {
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	writeICSLine(bw, "BEGIN:VCALENDAR")
+	writeICSLine(bw, "VERSION:2.0")
+	writeICSLine(bw, "PRODID:-//srebot//rota//EN")
+	writeICSLine(bw, "CALSCALE:GREGORIAN")
+	writeICSLine(bw, "METHOD:PUBLISH")
+	writeICSLine(bw, "X-WR-CALNAME:"+escapeICSText(name))
+
+	for _, s := range shifts {
+		uid := fmt.Sprintf("%x@srebot", sha1.Sum([]byte(s.Team+"|"+s.Tier+"|"+s.Email+"|"+s.Start.UTC().String())))
+
+		writeICSLine(bw, "BEGIN:VEVENT")
+		writeICSLine(bw, "UID:"+uid)
+		writeICSLine(bw, "DTSTAMP:"+stamp)
+		if s.WholeDays() {
+			writeICSLine(bw, "DTSTART;VALUE=DATE:"+s.Start.Format("20060102"))
+			writeICSLine(bw, "DTEND;VALUE=DATE:"+s.End.Format("20060102"))
+		} else {
+			writeICSLine(bw, "DTSTART:"+s.Start.UTC().Format("20060102T150405Z"))
+			writeICSLine(bw, "DTEND:"+s.End.UTC().Format("20060102T150405Z"))
+		}
+		writeICSLine(bw, "SUMMARY:"+escapeICSText(fmt.Sprintf("%s %s on call: %s", s.Team, s.Tier, s.Email)))
+		writeICSLine(bw, "ATTENDEE:mailto:"+s.Email)
+		writeICSLine(bw, "TRANSP:TRANSPARENT")
+		writeICSLine(bw, "END:VEVENT")
+	}
+
+	writeICSLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// writeICSLine writes a content line folded at 75 octets as RFC 5545 requires.
+func writeICSLine(w *bufio.Writer, line string) {
+	for len(line) > 75 {
+		cut := 75
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+	}
+	w.WriteString(line + "\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
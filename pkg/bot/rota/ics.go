@@ -0,0 +1,157 @@
+package rota
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dombo/hiberBot/pkg/bot/ical"
+)
+
+// ICSProvider reads shifts from iCalendar files, one file or http(s) URL per
+// team. Events follow the same conventions as the GoogleProvider: the summary
+// prefix names the tier and the first attendee is on call. Recurring events
+// are expanded. Dates and times without a time zone are in the team's zone.
+// The files are read once on creation and again on every Load.
+type ICSProvider struct {
+	sources   map[string]string
+	tiers     []Tier
+	locations Locations
+	client    *http.Client
+
+	mu     sync.RWMutex
+	events map[string][]ical.Event
+}
+
+// NewICSProvider reads the sources (team name to file path or URL).
+func NewICSProvider(sources map[string]string, tiers []Tier, locations Locations) (*ICSProvider, error) {
+	p := &ICSProvider{
+		sources:   sources,
+		tiers:     tiers,
+		locations: locations,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		events:    map[string][]ical.Event{},
+	}
+	if err := p.Load(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Load (re)reads every source. When a source fails the previously read events
+// of that team are kept.
+func (p *ICSProvider) Load() error {
+	var failed []string
+	for team, source := range p.sources {
+		events, err := p.read(source, p.locations.Of(team))
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", team, err))
+			continue
+		}
+
+		p.mu.Lock()
+		p.events[team] = events
+		p.mu.Unlock()
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to read rota calendars: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func (p *ICSProvider) read(source string, loc *time.Location) ([]ical.Event, error) {
+	var r io.ReadCloser
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		resp, err := p.client.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		r = f
+	}
+	defer r.Close()
+
+	return ical.Parse(r, loc)
+}
+
+func (p *ICSProvider) occurrences(team string, from, to time.Time) ([]ical.Event, []error, error) {
+	p.mu.RLock()
+	events, ok := p.events[team]
+	p.mu.RUnlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("no rota calendar configured for team %q", team)
+	}
+
+	occurrences, errs := ical.Expand(events, from, to)
+	return occurrences, errs, nil
+}
+
+func (p *ICSProvider) Shifts(team string, from, to time.Time) ([]Shift, error) {
+	occurrences, _, err := p.occurrences(team, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var shifts []Shift
+	for _, e := range occurrences {
+		tier, ok := tierFromSummary(p.tiers, e.Summary)
+		if !ok || len(e.Attendees) == 0 {
+			continue
+		}
+
+		shifts = append(shifts, Shift{
+			Team:  team,
+			Tier:  tier,
+			Email: e.Attendees[0],
+			Start: e.Start,
+			End:   e.End,
+		})
+	}
+	return shifts, nil
+}
+
+// Inspect reports rota events without attendees, events with several
+// attendees and recurring events whose rules can't be expanded.
+func (p *ICSProvider) Inspect(team string, from, to time.Time) ([]Problem, error) {
+	occurrences, errs, err := p.occurrences(team, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	for _, err := range errs {
+		problems = append(problems, Problem{Team: team, Start: from, Description: err.Error()})
+	}
+
+	for _, e := range occurrences {
+		tier, ok := tierFromSummary(p.tiers, e.Summary)
+		if !ok || len(e.Attendees) == 1 {
+			continue
+		}
+
+		problem := Problem{Team: team, Tier: tier, Start: e.Start}
+		if len(e.Attendees) == 0 {
+			problem.Description = fmt.Sprintf("event %q on %s has no attendees", e.Summary, e.Start.Format("Mon 2 Jan"))
+		} else {
+			problem.Description = fmt.Sprintf("event %q on %s has %d attendees, only %s is on call",
+				e.Summary, e.Start.Format("Mon 2 Jan"), len(e.Attendees), e.Attendees[0])
+		}
+		problems = append(problems, problem)
+	}
+
+	return problems, nil
+}
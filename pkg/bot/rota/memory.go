@@ -0,0 +1,78 @@
+package rota
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryProvider keeps shifts in memory. It is mostly useful for tests and
+// as the storage behind providers that load their shifts up front.
+type MemoryProvider struct {
+	mu     sync.RWMutex
+	shifts []Shift
+}
+
+// NewMemoryProvider returns a provider that knows the given shifts.
+func NewMemoryProvider(shifts ...Shift) *MemoryProvider {
+	p := &MemoryProvider{}
+	p.Add(shifts...)
+	return p
+}
+
+// Add stores more shifts. Shifts without a team belong to the DefaultTeam.
+func (p *MemoryProvider) Add(shifts ...Shift) {
+	shifts = withTeam(shifts)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.shifts = append(p.shifts, shifts...)
+}
+
+// Replace swaps all stored shifts for the given ones at once, lookups see
+// either the old or the new shifts.
+func (p *MemoryProvider) Replace(shifts ...Shift) {
+	shifts = withTeam(shifts)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.shifts = shifts
+}
+
+// withTeam returns a copy of the shifts in which shifts without a team belong to the DefaultTeam
+func withTeam(shifts []Shift) []Shift {
+	copied := make([]Shift, 0, len(shifts))
+	for _, s := range shifts {
+		if s.Team == "" {
+			s.Team = DefaultTeam
+		}
+		copied = append(copied, s)
+	}
+	return copied
+}
+
+// Create stores the shift as a regular shift.
+func (p *MemoryProvider) Create(shift Shift) error {
+	shift.Override = false
+	p.Add(shift)
+	return nil
+}
+
+// Override stores the shift as an override.
+func (p *MemoryProvider) Override(shift Shift) error {
+	shift.Override = true
+	p.Add(shift)
+	return nil
+}
+
+func (p *MemoryProvider) Shifts(team string, from, to time.Time) ([]Shift, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var shifts []Shift
+	for _, s := range p.shifts {
+		if s.Team == team && s.Overlaps(from, to) {
+			shifts = append(shifts, s)
+		}
+	}
+	return shifts, nil
+}
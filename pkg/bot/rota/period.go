@@ -0,0 +1,159 @@
+package rota
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/now"
+)
+
+// Period is the span of time a date expression refers to. Single is set when
+// the expression names a single day, e.g. "tomorrow" or "on 2020-06-18".
+type Period struct {
+	From   time.Time
+	To     time.Time
+	Single bool
+}
+
+// dateLayouts are the absolute date formats understood by ParsePeriod, the
+// layouts without a year refer to the year of the reference time.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2 Jan 2006",
+	"2 January 2006",
+	"Jan 2 2006",
+	"January 2 2006",
+	"2 Jan",
+	"2 January",
+	"Jan 2",
+	"January 2",
+}
+
+// ParsePeriod parses a date expression relative to ref. It understands
+//
+//	today, tomorrow, yesterday
+//	monday … sunday, next monday … next sunday
+//	this week, next week, last week, this month, next month, last month
+//	in 3 days, in 2 weeks
+//	2020-06-18, 18 Jun, Jun 18 2020 (optionally prefixed with "on")
+//	ranges of the above: "monday to friday", "from 2020-06-18 until 2020-06-25"
+//
+// The end of a range is relative to its start, so "friday to monday" runs
+// over the weekend. An empty expression means today.
+func ParsePeriod(expr string, ref time.Time) (Period, error) {
+	expr = strings.ToLower(strings.Join(strings.Fields(expr), " "))
+	expr = strings.TrimPrefix(expr, "from ")
+
+	for _, sep := range []string{" to ", " until ", " - "} {
+		if i := strings.Index(expr, sep); i >= 0 {
+			start, err := parseSingle(expr[:i], ref)
+			if err != nil {
+				return Period{}, err
+			}
+			end, err := parseSingle(expr[i+len(sep):], start.From)
+			if err != nil {
+				return Period{}, err
+			}
+			if end.To.Before(start.From) {
+				return Period{}, fmt.Errorf("%q ends before it starts", expr)
+			}
+			return Period{From: start.From, To: end.To}, nil
+		}
+	}
+
+	return parseSingle(expr, ref)
+}
+
+func parseSingle(expr string, ref time.Time) (Period, error) {
+	expr = strings.TrimPrefix(strings.TrimSpace(expr), "on ")
+	n := now.With(ref)
+
+	switch expr {
+	case "", "today":
+		return day(ref), nil
+	case "tomorrow":
+		return day(ref.AddDate(0, 0, 1)), nil
+	case "yesterday":
+		return day(ref.AddDate(0, 0, -1)), nil
+	case "this week":
+		return Period{From: n.BeginningOfWeek(), To: n.EndOfWeek()}, nil
+	case "next week":
+		next := now.With(ref.AddDate(0, 0, 7))
+		return Period{From: next.BeginningOfWeek(), To: next.EndOfWeek()}, nil
+	case "last week":
+		last := now.With(ref.AddDate(0, 0, -7))
+		return Period{From: last.BeginningOfWeek(), To: last.EndOfWeek()}, nil
+	case "this month":
+		return Period{From: n.BeginningOfMonth(), To: n.EndOfMonth()}, nil
+	case "last month":
+		last := now.With(n.BeginningOfMonth().AddDate(0, -1, 0))
+		return Period{From: last.BeginningOfMonth(), To: last.EndOfMonth()}, nil
+	case "next month":
+		next := now.With(n.BeginningOfMonth().AddDate(0, 1, 0))
+		return Period{From: next.BeginningOfMonth(), To: next.EndOfMonth()}, nil
+	}
+
+	if strings.HasPrefix(expr, "in ") {
+		return parseOffset(strings.TrimPrefix(expr, "in "), ref)
+	}
+
+	next := strings.HasPrefix(expr, "next ")
+	if wd, ok := parseWeekday(strings.TrimPrefix(expr, "next ")); ok {
+		if next {
+			start := now.With(ref.AddDate(0, 0, 7)).BeginningOfWeek()
+			return day(start.AddDate(0, 0, (int(wd)-int(start.Weekday())+7)%7)), nil
+		}
+		return day(ref.AddDate(0, 0, (int(wd)-int(ref.Weekday())+7)%7)), nil
+	}
+
+	for _, layout := range dateLayouts {
+		t, err := time.ParseInLocation(layout, expr, ref.Location())
+		if err != nil {
+			continue
+		}
+		if t.Year() == 0 {
+			t = t.AddDate(ref.Year(), 0, 0)
+		}
+		return day(t), nil
+	}
+
+	return Period{}, fmt.Errorf("I don't understand the date %q", expr)
+}
+
+// parseOffset parses "3 days" or "2 weeks" into the day that far from ref.
+func parseOffset(expr string, ref time.Time) (Period, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 2 {
+		return Period{}, fmt.Errorf("I don't understand %q, try \"in 3 days\"", expr)
+	}
+
+	count, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return Period{}, fmt.Errorf("%q is not a number", fields[0])
+	}
+
+	switch strings.TrimSuffix(fields[1], "s") {
+	case "day":
+		return day(ref.AddDate(0, 0, count)), nil
+	case "week":
+		return day(ref.AddDate(0, 0, 7*count)), nil
+	}
+	return Period{}, fmt.Errorf("I don't understand %q, try days or weeks", fields[1])
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		name := strings.ToLower(wd.String())
+		if s == name || s == name[:3] {
+			return wd, true
+		}
+	}
+	return 0, false
+}
+
+func day(t time.Time) Period {
+	n := now.With(t)
+	return Period{From: n.BeginningOfDay(), To: n.EndOfDay(), Single: true}
+}
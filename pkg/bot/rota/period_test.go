@@ -0,0 +1,85 @@
+package rota
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePeriod(t *testing.T) {
+	ref := june(18, 10) // a Thursday
+	end := func(t time.Time) time.Time { return t.AddDate(0, 0, 1).Add(-time.Nanosecond) }
+
+	tests := []struct {
+		expr   string
+		from   time.Time
+		to     time.Time
+		single bool
+	}{
+		{"", june(18, 0), end(june(18, 0)), true},
+		{"today", june(18, 0), end(june(18, 0)), true},
+		{"tomorrow", june(19, 0), end(june(19, 0)), true},
+		{"Yesterday", june(17, 0), end(june(17, 0)), true},
+		{"thursday", june(18, 0), end(june(18, 0)), true},
+		{"friday", june(19, 0), end(june(19, 0)), true},
+		{"wed", june(24, 0), end(june(24, 0)), true},
+		{"next monday", june(22, 0), end(june(22, 0)), true},
+		{"next sunday", june(21, 0), end(june(21, 0)), true},
+		{"this week", june(14, 0), end(june(20, 0)), false},
+		{"next week", june(21, 0), end(june(27, 0)), false},
+		{"last week", june(7, 0), end(june(13, 0)), false},
+		{"this month", june(1, 0), end(june(30, 0)), false},
+		{"last month", june(1, 0).AddDate(0, -1, 0), end(june(1, 0).AddDate(0, 0, -1)), false},
+		{"next month", june(1, 0).AddDate(0, 1, 0), end(june(1, 0).AddDate(0, 2, -1)), false},
+		{"in 3 days", june(21, 0), end(june(21, 0)), true},
+		{"in 2 weeks", june(1, 0).AddDate(0, 1, 1), end(june(1, 0).AddDate(0, 1, 1)), true},
+		{"2020-06-25", june(25, 0), end(june(25, 0)), true},
+		{"on 25 Jun", june(25, 0), end(june(25, 0)), true},
+		{"Jun 25 2020", june(25, 0), end(june(25, 0)), true},
+		{"friday to monday", june(19, 0), end(june(22, 0)), false},
+		{"from  2020-06-18 until 2020-06-25", june(18, 0), end(june(25, 0)), false},
+		{"today - tomorrow", june(18, 0), end(june(19, 0)), false},
+	}
+	for _, tt := range tests {
+		p, err := ParsePeriod(tt.expr, ref)
+		if err != nil {
+			t.Errorf("ParsePeriod(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if !p.From.Equal(tt.from) || !p.To.Equal(tt.to) || p.Single != tt.single {
+			t.Errorf("ParsePeriod(%q) = %v - %v single %v, want %v - %v single %v", tt.expr, p.From, p.To, p.Single, tt.from, tt.to, tt.single)
+		}
+	}
+}
+
+func TestParsePeriodRangeFromEveryDay(t *testing.T) {
+	for day := 15; day <= 21; day++ {
+		ref := june(day, 10)
+		p, err := ParsePeriod("monday to friday", ref)
+		if err != nil {
+			t.Errorf("ParsePeriod on %s failed: %v", ref.Weekday(), err)
+			continue
+		}
+		if p.From.Weekday() != time.Monday || p.From.Before(june(day, 0)) {
+			t.Errorf("ParsePeriod on %s starts %v, want the next Monday", ref.Weekday(), p.From)
+		}
+		if want := p.From.AddDate(0, 0, 5).Add(-time.Nanosecond); !p.To.Equal(want) {
+			t.Errorf("ParsePeriod on %s ends %v, want %v", ref.Weekday(), p.To, want)
+		}
+	}
+}
+
+func TestParsePeriodErrors(t *testing.T) {
+	ref := june(18, 10)
+	for _, expr := range []string{
+		"blursday",
+		"in three days",
+		"in 3 fortnights",
+		"in 3",
+		"2020-06-25 to 2020-06-18",
+		"monday to someday",
+	} {
+		if p, err := ParsePeriod(expr, ref); err == nil {
+			t.Errorf("ParsePeriod(%q) = %v - %v, want an error", expr, p.From, p.To)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Package rota answers who is on call for a team and provides the different
+// sources a rota can be read from.
+package rota
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/now"
+)
+
+// DefaultTeam is the team every shift belongs to when only a single rota is
+// configured.
+const DefaultTeam = "default"
+
+// ErrNoShift is returned when nobody is on call for the requested tier.
+var ErrNoShift = errors.New("no shift found")
+
+// Shift is a single on-call assignment of a person to a tier of a team. An
+// Override shift takes precedence over the regular shifts it overlaps.
+type Shift struct {
+	Team     string
+	Tier     string
+	Email    string
+	Start    time.Time
+	End      time.Time
+	Override bool
+}
+
+// Covers reports whether t falls within the shift.
+func (s Shift) Covers(t time.Time) bool {
+	return !t.Before(s.Start) && t.Before(s.End)
+}
+
+// WholeDays reports whether the shift starts and ends at midnight.
+func (s Shift) WholeDays() bool {
+	return s.Start.Equal(now.With(s.Start).BeginningOfDay()) && s.End.Equal(now.With(s.End).BeginningOfDay())
+}
+
+// Clip returns the part of the shift that falls within [from, to).
+func (s Shift) Clip(from, to time.Time) Shift {
+	if from.After(s.Start) {
+		s.Start = from
+	}
+	if to.Before(s.End) {
+		s.End = to
+	}
+	return s
+}
+
+// Overlaps reports whether the shift covers any part of [from, to).
+func (s Shift) Overlaps(from, to time.Time) bool {
+	return s.Start.Before(to) && s.End.After(from)
+}
+
+// Locations maps teams to their time zones, in which dates without a time
+// of day are read. Teams that aren't listed use the local time zone.
+type Locations map[string]*time.Location
+
+// Of returns the time zone of the team.
+func (l Locations) Of(team string) *time.Location {
+	if loc, ok := l[team]; ok && loc != nil {
+		return loc
+	}
+	return time.Local
+}
+
+// Tier is an escalation level, the calendar summary prefix that marks its
+// events, e.g. "L1:", and the window a lookup of its shift covers.
+type Tier struct {
+	Name   string
+	Prefix string
+	Window Window
+}
+
+// DefaultTiers are used when no tiers are configured: a daily L1 and a weekly L2.
+var DefaultTiers = []Tier{
+	{Name: "L1", Prefix: "L1:", Window: Day},
+	{Name: "L2", Prefix: "L2:", Window: Week},
+}
+
+// A Provider knows the shifts of one or more teams.
+type Provider interface {
+	// Shifts returns every shift of the team that overlaps [from, to).
+	Shifts(team string, from, to time.Time) ([]Shift, error)
+}
+
+// A Writer is a Provider whose shifts can be changed.
+type Writer interface {
+	Provider
+
+	// Create stores a new regular shift.
+	Create(shift Shift) error
+
+	// Override stores the shift as an override of the shifts it overlaps.
+	Override(shift Shift) error
+}
+
+// A Loader is a Provider that reads its shifts up front and can read them
+// again to pick up changes.
+type Loader interface {
+	Provider
+
+	Load() error
+}
+
+// OnCall returns who is on call for the tier of a team at the given time. The
+// regular shift is looked up within the tier's window around at, so a weekly
+// tier finds its shift anywhere in the week. An override covering at wins over
+// the regular shift, then a regular shift covering at, and otherwise the last
+// regular shift in the window.
+func OnCall(p Provider, team string, tier Tier, at time.Time) (*Shift, error) {
+	from, to := tier.Window.Around(at)
+	shifts, err := p.Shifts(team, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	shift := pick(shifts, tier, at)
+	if shift == nil {
+		return nil, ErrNoShift
+	}
+	return shift, nil
+}
+
+// NextChange returns the first time after the given one at which who is on
+// call for one of the tiers may change: a shift or override starting or
+// ending, or a tier's next window. It looks a week ahead at most.
+func NextChange(p Provider, team string, tiers []Tier, after time.Time) (time.Time, error) {
+	next := after.AddDate(0, 0, 7)
+	shifts, err := p.Shifts(team, after, next)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	consider := func(t time.Time) {
+		if t.After(after) && t.Before(next) {
+			next = t
+		}
+	}
+	for _, tier := range tiers {
+		_, end := tier.Window.Around(after)
+		consider(end.Add(time.Nanosecond)) // the end of a calendar window is its last nanosecond
+	}
+	for _, s := range shifts {
+		consider(s.Start)
+		consider(s.End)
+	}
+
+	return next, nil
+}
+
+// pick applies the rules of OnCall to shifts that were already fetched.
+func pick(shifts []Shift, tier Tier, at time.Time) *Shift {
+	from, to := tier.Window.Around(at)
+
+	var regular, covering, override *Shift
+	for i := range shifts {
+		s := &shifts[i]
+		if s.Tier != tier.Name || !s.Overlaps(from, to) {
+			continue
+		}
+		switch {
+		case s.Override && s.Covers(at):
+			override = s
+		case s.Override:
+		case s.Covers(at):
+			regular, covering = s, s
+		default:
+			regular = s
+		}
+	}
+
+	switch {
+	case override != nil:
+		return override
+	case covering != nil:
+		return covering
+	}
+	return regular
+}
+
+// Resolve returns the shifts as they apply. An override wins over the regular
+// shifts of its tier, as in OnCall, so the parts of regular shifts that an
+// override covers are cut out and fully covered regular shifts are left out.
+func Resolve(shifts []Shift) []Shift {
+	var resolved []Shift
+	for _, s := range shifts {
+		if s.Override {
+			resolved = append(resolved, s)
+			continue
+		}
+
+		parts := []Shift{s}
+		for _, o := range shifts {
+			if !o.Override || o.Team != s.Team || o.Tier != s.Tier {
+				continue
+			}
+			var left []Shift
+			for _, p := range parts {
+				if !p.Overlaps(o.Start, o.End) {
+					left = append(left, p)
+					continue
+				}
+				if p.Start.Before(o.Start) {
+					left = append(left, p.Clip(p.Start, o.Start))
+				}
+				if p.End.After(o.End) {
+					left = append(left, p.Clip(o.End, p.End))
+				}
+			}
+			parts = left
+		}
+		resolved = append(resolved, parts...)
+	}
+	return resolved
+}
+
+// tierFromSummary returns the name of the tier whose prefix the summary
+// starts with.
+func tierFromSummary(tiers []Tier, summary string) (string, bool) {
+	for _, t := range tiers {
+		if strings.HasPrefix(summary, t.Prefix) {
+			return t.Name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,230 @@
+package rota
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// june returns the time on the day of June 2020, the 15th is a Monday
+func june(day, hour int) time.Time {
+	return time.Date(2020, time.June, day, hour, 0, 0, 0, time.UTC)
+}
+
+func TestPick(t *testing.T) {
+	l1 := Tier{Name: "L1", Prefix: "L1:", Window: Day}
+	l2 := Tier{Name: "L2", Prefix: "L2:", Window: Week}
+
+	tests := []struct {
+		name   string
+		shifts []Shift
+		tier   Tier
+		at     time.Time
+		want   string
+	}{
+		{
+			name: "no shifts",
+			tier: l1,
+			at:   june(18, 10),
+		},
+		{
+			name:   "covering shift",
+			shifts: []Shift{{Tier: "L1", Email: "alice", Start: june(18, 0), End: june(19, 0)}},
+			tier:   l1,
+			at:     june(18, 10),
+			want:   "alice",
+		},
+		{
+			name:   "shift anywhere in the window",
+			shifts: []Shift{{Tier: "L2", Email: "alice", Start: june(15, 0), End: june(16, 0)}},
+			tier:   l2,
+			at:     june(18, 10),
+			want:   "alice",
+		},
+		{
+			name: "covering shift wins over a later one",
+			shifts: []Shift{
+				{Tier: "L2", Email: "alice", Start: june(15, 0), End: june(17, 0)},
+				{Tier: "L2", Email: "bob", Start: june(17, 0), End: june(20, 0)},
+			},
+			tier: l2,
+			at:   june(16, 10),
+			want: "alice",
+		},
+		{
+			name: "last shift when none covers",
+			shifts: []Shift{
+				{Tier: "L2", Email: "alice", Start: june(15, 0), End: june(16, 0)},
+				{Tier: "L2", Email: "bob", Start: june(16, 0), End: june(17, 0)},
+			},
+			tier: l2,
+			at:   june(19, 10),
+			want: "bob",
+		},
+		{
+			name: "override covering at",
+			shifts: []Shift{
+				{Tier: "L1", Email: "carol", Start: june(18, 9), End: june(18, 12), Override: true},
+				{Tier: "L1", Email: "alice", Start: june(18, 0), End: june(19, 0)},
+			},
+			tier: l1,
+			at:   june(18, 10),
+			want: "carol",
+		},
+		{
+			name: "override not covering at",
+			shifts: []Shift{
+				{Tier: "L1", Email: "alice", Start: june(18, 0), End: june(19, 0)},
+				{Tier: "L1", Email: "carol", Start: june(18, 14), End: june(18, 16), Override: true},
+			},
+			tier: l1,
+			at:   june(18, 10),
+			want: "alice",
+		},
+		{
+			name: "other tiers and windows",
+			shifts: []Shift{
+				{Tier: "L2", Email: "bob", Start: june(18, 0), End: june(19, 0)},
+				{Tier: "L1", Email: "alice", Start: june(17, 0), End: june(18, 0)},
+			},
+			tier: l1,
+			at:   june(18, 10),
+		},
+	}
+	for _, tt := range tests {
+		got := ""
+		if s := pick(tt.shifts, tt.tier, tt.at); s != nil {
+			got = s.Email
+		}
+		if got != tt.want {
+			t.Errorf("%s: pick() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOnCall(t *testing.T) {
+	tier := Tier{Name: "L1", Prefix: "L1:", Window: Day}
+	p := NewMemoryProvider(
+		Shift{Tier: "L1", Email: "alice", Start: june(18, 0), End: june(19, 0)},
+		Shift{Team: "payments", Tier: "L1", Email: "bob", Start: june(18, 0), End: june(19, 0)},
+	)
+
+	shift, err := OnCall(p, DefaultTeam, tier, june(18, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shift.Email != "alice" {
+		t.Errorf("OnCall() = %q, want alice", shift.Email)
+	}
+
+	shift, err = OnCall(p, "payments", tier, june(18, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shift.Email != "bob" {
+		t.Errorf("OnCall() for payments = %q, want bob", shift.Email)
+	}
+
+	if _, err := OnCall(p, DefaultTeam, tier, june(19, 10)); err != ErrNoShift {
+		t.Errorf("OnCall() without a shift = %v, want ErrNoShift", err)
+	}
+}
+
+func TestOnCallInTeamZone(t *testing.T) {
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	if err != nil {
+		t.Skip("no time zone database", err)
+	}
+
+	dir, err := ioutil.TempDir("", "rota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rota.json")
+	err = ioutil.WriteFile(path, []byte(`{"shifts": [
+		{"tier": "L1", "email": "alice", "start": "2020-06-17", "end": "2020-06-17"},
+		{"tier": "L1", "email": "bob", "start": "2020-06-18", "end": "2020-06-18"}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewFileProvider(path, Locations{DefaultTeam: sydney})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Early on the 18th in Sydney it is still the 17th in most other zones
+	tier := Tier{Name: "L1", Prefix: "L1:", Window: Day}
+	shift, err := OnCall(p, DefaultTeam, tier, time.Date(2020, time.June, 18, 8, 0, 0, 0, sydney))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shift.Email != "bob" {
+		t.Errorf("OnCall() = %q, want bob", shift.Email)
+	}
+	if want := time.Date(2020, time.June, 18, 0, 0, 0, 0, sydney); !shift.Start.Equal(want) {
+		t.Errorf("shift starts at %v, want %v", shift.Start, want)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	week := Shift{Team: "sre", Tier: "L2", Email: "alice", Start: june(15, 0), End: june(22, 0)}
+	override := func(email string, start, end time.Time) Shift {
+		return Shift{Team: "sre", Tier: "L2", Email: email, Start: start, End: end, Override: true}
+	}
+
+	tests := []struct {
+		name   string
+		shifts []Shift
+		want   []Shift
+	}{
+		{
+			name:   "no overrides",
+			shifts: []Shift{week},
+			want:   []Shift{week},
+		},
+		{
+			name:   "fully covered",
+			shifts: []Shift{week, override("bob", june(14, 0), june(22, 0))},
+			want:   []Shift{override("bob", june(14, 0), june(22, 0))},
+		},
+		{
+			name:   "covered in the middle",
+			shifts: []Shift{week, override("bob", june(17, 0), june(18, 0))},
+			want: []Shift{
+				week.Clip(june(15, 0), june(17, 0)),
+				week.Clip(june(18, 0), june(22, 0)),
+				override("bob", june(17, 0), june(18, 0)),
+			},
+		},
+		{
+			name: "overrides of other tiers and teams",
+			shifts: []Shift{
+				week,
+				{Team: "sre", Tier: "L1", Email: "bob", Start: june(15, 0), End: june(22, 0), Override: true},
+				{Team: "payments", Tier: "L2", Email: "carol", Start: june(15, 0), End: june(22, 0), Override: true},
+			},
+			want: []Shift{
+				week,
+				{Team: "sre", Tier: "L1", Email: "bob", Start: june(15, 0), End: june(22, 0), Override: true},
+				{Team: "payments", Tier: "L2", Email: "carol", Start: june(15, 0), End: june(22, 0), Override: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := Resolve(tt.shifts)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: Resolve() = %+v, want %+v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: shift %d = %+v, want %+v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
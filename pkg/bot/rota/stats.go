@@ -0,0 +1,87 @@
+package rota
+
+import (
+	"sort"
+	"time"
+)
+
+// Load is how much on-call a person did over a period.
+type Load struct {
+	Email       string
+	Windows     map[string]int // windows covered per tier name, e.g. days of L1
+	WeekendDays int            // Saturdays and Sundays on call for any tier
+	HolidayDays int            // public holidays on call for any tier
+	CoveredDays int            // days on call for any tier through an override, i.e. a swap or cover
+}
+
+// StatsOptions control what Stats counts.
+type StatsOptions struct {
+	Holidays []Holiday // public holidays of the team between from and to
+}
+
+// Stats works out who was on call for each window of each tier of a team
+// between from and to, taking overrides into account, and sums it up per
+// person. The result is sorted by email.
+func Stats(p Provider, team string, tiers []Tier, from, to time.Time, opts StatsOptions) ([]Load, error) {
+	shifts, err := p.Shifts(team, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	loads := map[string]*Load{}
+	load := func(email string) *Load {
+		if loads[email] == nil {
+			loads[email] = &Load{Email: email, Windows: map[string]int{}}
+		}
+		return loads[email]
+	}
+
+	for _, tier := range tiers {
+		for at := from; at.Before(to); {
+			start, end := tier.Window.Around(at)
+			at = end.Add(time.Nanosecond)
+
+			if s := pick(shifts, tier, start); s != nil {
+				load(s.Email).Windows[tier.Name]++
+			}
+		}
+	}
+
+	holidays := map[string]bool{}
+	for _, h := range opts.Holidays {
+		holidays[h.Date.Format("2006-01-02")] = true
+	}
+
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		weekend := d.Weekday() == time.Saturday || d.Weekday() == time.Sunday
+		holiday := holidays[d.Format("2006-01-02")]
+
+		onCall, covering := map[string]bool{}, map[string]bool{}
+		for _, tier := range tiers {
+			if s := pick(shifts, tier, d); s != nil {
+				onCall[s.Email] = true
+				if s.Override {
+					covering[s.Email] = true
+				}
+			}
+		}
+		for email := range onCall {
+			if weekend {
+				load(email).WeekendDays++
+			}
+			if holiday {
+				load(email).HolidayDays++
+			}
+			if covering[email] {
+				load(email).CoveredDays++
+			}
+		}
+	}
+
+	result := make([]Load, 0, len(loads))
+	for _, l := range loads {
+		result = append(result, *l)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Email < result[j].Email })
+	return result, nil
+}
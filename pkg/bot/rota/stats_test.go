@@ -0,0 +1,36 @@
+package rota
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	l1 := Tier{Name: "L1", Prefix: "L1:", Window: Day}
+	p := NewMemoryProvider(
+		Shift{Tier: "L1", Email: "alice", Start: june(15, 0), End: june(29, 0)},
+		// A swap spanning a week is seven days of cover, not seven swaps
+		Shift{Tier: "L1", Email: "bob", Start: june(15, 0), End: june(22, 0), Override: true},
+		Shift{Tier: "L1", Email: "carol", Start: june(24, 0), End: june(25, 0), Override: true},
+	)
+
+	loads, err := Stats(p, DefaultTeam, []Tier{l1}, june(15, 0), june(29, 0), StatsOptions{
+		Holidays: []Holiday{{Date: june(25, 0), Name: "Midsummer"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Load{
+		{Email: "alice", Windows: map[string]int{"L1": 6}, WeekendDays: 2, HolidayDays: 1},
+		{Email: "bob", Windows: map[string]int{"L1": 7}, WeekendDays: 2, CoveredDays: 7},
+		{Email: "carol", Windows: map[string]int{"L1": 1}, CoveredDays: 1},
+	}
+	if len(loads) != len(want) {
+		t.Fatalf("Stats() = %+v, want %+v", loads, want)
+	}
+	for i, w := range want {
+		got := loads[i]
+		if got.Email != w.Email || got.Windows["L1"] != w.Windows["L1"] || got.WeekendDays != w.WeekendDays ||
+			got.HolidayDays != w.HolidayDays || got.CoveredDays != w.CoveredDays {
+			t.Errorf("Stats()[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+}
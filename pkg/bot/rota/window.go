@@ -0,0 +1,134 @@
+package rota
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/now"
+)
+
+// Window is the period a tier's shift covers. It is either one of Day, Week
+// and Month or a shift length such as "12h".
+type Window string
+
+const (
+	Day   Window = "day"
+	Week  Window = "week"
+	Month Window = "month"
+)
+
+// Validate checks that the window is a known period or a positive duration.
+func (w Window) Validate() error {
+	switch w {
+	case Day, Week, Month:
+		return nil
+	}
+
+	d, err := time.ParseDuration(string(w))
+	if err != nil {
+		return fmt.Errorf("invalid window %q: must be day, week, month or a duration", w)
+	}
+	if d <= 0 {
+		return fmt.Errorf("invalid window %q: duration must be positive", w)
+	}
+	return nil
+}
+
+// shiftEpoch anchors shift lengths of a day or more. It is a Monday, so a
+// 168h window runs from Monday to Monday and a 48h window always starts on
+// the same days, whichever day it is looked up on.
+var shiftEpoch = time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Around returns the window that contains t. Shift lengths shorter than a day
+// are counted from midnight, so 12h windows are 00:00-12:00 and 12:00-24:00.
+// Longer shift lengths are counted from shiftEpoch in t's time zone, whole
+// days by the calendar so the windows start at midnight across DST changes.
+func (w Window) Around(t time.Time) (time.Time, time.Time) {
+	n := now.With(t)
+	switch w {
+	case Day:
+		return n.BeginningOfDay(), n.EndOfDay()
+	case Week:
+		return n.BeginningOfWeek(), n.EndOfWeek()
+	case Month:
+		return n.BeginningOfMonth(), n.EndOfMonth()
+	}
+
+	d, err := time.ParseDuration(string(w))
+	if err != nil || d <= 0 {
+		return n.BeginningOfDay(), n.EndOfDay()
+	}
+
+	start := n.BeginningOfDay()
+	if d < 24*time.Hour {
+		start = start.Add(t.Sub(start) / d * d)
+		return start, start.Add(d)
+	}
+
+	epoch := time.Date(shiftEpoch.Year(), shiftEpoch.Month(), shiftEpoch.Day(), 0, 0, 0, 0, t.Location())
+	if d%(24*time.Hour) != 0 {
+		start = epoch.Add(time.Duration(floorDiv(int64(t.Sub(epoch)), int64(d))) * d)
+		return start, start.Add(d)
+	}
+
+	days := int64(d / (24 * time.Hour))
+	// Days between the epoch and t by the calendar, counted in UTC to ignore DST
+	elapsed := int64(time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC).Sub(shiftEpoch) / (24 * time.Hour))
+	start = epoch.AddDate(0, 0, int(floorDiv(elapsed, days)*days))
+	return start, start.AddDate(0, 0, int(days))
+}
+
+// floorDiv divides rounding towards minus infinity, so times before the epoch
+// fall into the window that starts before them
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// Describe returns how the window reads in a sentence, e.g. "this week".
+func (w Window) Describe() string {
+	switch w {
+	case Day:
+		return "today"
+	case Week:
+		return "this week"
+	case Month:
+		return "this month"
+	}
+	return "this shift"
+}
+
+// DescribeAt describes the window containing t for a sentence read at ref,
+// e.g. "today" when both fall in the same window or "on Thu 18 Jun" otherwise.
+func (w Window) DescribeAt(t, ref time.Time) string {
+	from, _ := w.Around(t)
+	if refFrom, _ := w.Around(ref); from.Equal(refFrom) {
+		return w.Describe()
+	}
+
+	switch w {
+	case Day:
+		return from.Format("on Mon 2 Jan")
+	case Week:
+		return from.Format("the week of Mon 2 Jan")
+	case Month:
+		return from.Format("in January")
+	}
+	return from.Format("the shift from 15:04 on Mon 2 Jan")
+}
+
+// Unit names what a window counts in reports, e.g. "days".
+func (w Window) Unit() string {
+	switch w {
+	case Day:
+		return "days"
+	case Week:
+		return "weeks"
+	case Month:
+		return "months"
+	}
+	return "shifts"
+}
@@ -0,0 +1,72 @@
+package rota
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowAround(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("no time zone database", err)
+	}
+	date := func(loc *time.Location, year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, loc)
+	}
+	utc := func(year int, month time.Month, day, hour int) time.Time {
+		return date(time.UTC, year, month, day, hour)
+	}
+	last := -time.Nanosecond // calendar windows end on their last nanosecond
+
+	tests := []struct {
+		window   Window
+		at       time.Time
+		from, to time.Time
+	}{
+		{Day, utc(2020, 6, 18, 15), utc(2020, 6, 18, 0), utc(2020, 6, 19, 0).Add(last)},
+		{Week, utc(2020, 6, 18, 15), utc(2020, 6, 14, 0), utc(2020, 6, 21, 0).Add(last)},
+		{Month, utc(2020, 6, 18, 15), utc(2020, 6, 1, 0), utc(2020, 7, 1, 0).Add(last)},
+		{"12h", utc(2020, 6, 18, 3), utc(2020, 6, 18, 0), utc(2020, 6, 18, 12)},
+		{"12h", utc(2020, 6, 18, 15), utc(2020, 6, 18, 12), utc(2020, 6, 19, 0)},
+		{"8h", utc(2020, 6, 18, 16), utc(2020, 6, 18, 16), utc(2020, 6, 19, 0)},
+		// longer shift lengths don't start on the day they are looked up on
+		{"48h", utc(2020, 6, 18, 10), utc(2020, 6, 18, 0), utc(2020, 6, 20, 0)},
+		{"48h", utc(2020, 6, 19, 10), utc(2020, 6, 18, 0), utc(2020, 6, 20, 0)},
+		{"48h", utc(2020, 6, 20, 10), utc(2020, 6, 20, 0), utc(2020, 6, 22, 0)},
+		{"36h", utc(2020, 6, 18, 10), utc(2020, 6, 17, 0), utc(2020, 6, 18, 12)},
+		{"168h", utc(2020, 6, 15, 0), utc(2020, 6, 15, 0), utc(2020, 6, 22, 0)},
+		{"168h", utc(2020, 6, 18, 15), utc(2020, 6, 15, 0), utc(2020, 6, 22, 0)},
+		{"168h", utc(2020, 6, 14, 23), utc(2020, 6, 8, 0), utc(2020, 6, 15, 0)},
+		{"168h", utc(1999, 12, 31, 12), utc(1999, 12, 27, 0), utc(2000, 1, 3, 0)},
+		// whole days start at midnight across DST changes
+		{"48h", date(london, 2020, 3, 28, 12), date(london, 2020, 3, 28, 0), date(london, 2020, 3, 30, 0)},
+		{"48h", date(london, 2020, 3, 29, 12), date(london, 2020, 3, 28, 0), date(london, 2020, 3, 30, 0)},
+		// invalid windows fall back to the day
+		{"-1h", utc(2020, 6, 18, 15), utc(2020, 6, 18, 0), utc(2020, 6, 19, 0).Add(last)},
+	}
+	for _, tt := range tests {
+		from, to := tt.window.Around(tt.at)
+		if !from.Equal(tt.from) || !to.Equal(tt.to) {
+			t.Errorf("%s.Around(%v) = %v - %v, want %v - %v", tt.window, tt.at, from, to, tt.from, tt.to)
+		}
+	}
+}
+
+func TestWindowValidate(t *testing.T) {
+	tests := map[Window]bool{
+		Day:     true,
+		Week:    true,
+		Month:   true,
+		"12h":   true,
+		"168h":  true,
+		"0h":    false,
+		"-12h":  false,
+		"daily": false,
+		"":      false,
+	}
+	for window, valid := range tests {
+		if err := window.Validate(); (err == nil) != valid {
+			t.Errorf("%q.Validate() = %v, want valid %v", window, err, valid)
+		}
+	}
+}